@@ -7,8 +7,24 @@ import (
 	"github.com/catenacyber/ngolo-fuzzing/pkgtofuzzinput"
 )
 
-var exclude = flag.String("exclude", "", "comma-separated string pattern to exclude from functions")
-var limits = flag.String("limits", "", "comma-separated list of integer arguments to limit")
+var include = flag.String("include", "", "comma-separated regular expressions of functions to fuzz, like Decode or Buffer.Read.*")
+var exclude = flag.String("exclude", "", "comma-separated regular expressions of functions, methods and types to exclude, like Must.* or Buffer.Next")
+var configFile = flag.String("config", "", "json file with the configuration for the package, like std/regexp.json")
+var hooks = flag.String("hooks", "", "go file with functions like Pre_IntNgdotModSqrt, called before the function to decide if it gets called")
+var limits = flag.String("limits", "", "comma-separated list of integer arguments to limit, like Regexp.Split.n, GenerateKey.bits=512..4096 or Repeat.count<=1024")
+var panics = flag.String("panics", "", "comma-separated types of panic values which are not bugs, like big.ErrNaN, or Repeat=string|runtime.Error for some functions")
+var docpanics = flag.String("docpanics", "", "what to do with the functions documented to panic : expect their string and error panics (default), exclude them, or warn")
+var errorsMode = flag.String("errors", "", "what to do after a call returned an error : stop the input (default), continue with the next calls, or check the error and continue")
+var immutable = flag.Bool("immutable", false, "panic when a function modifies one of its byte slice arguments")
+var mutable = flag.String("mutable", "", "comma-separated regular expressions of functions allowed to modify their byte slice arguments, like Sort.*")
+var determinism = flag.Bool("determinism", false, "run each input twice, and panic when the results of the calls differ")
+var slow = flag.String("slow", "", "time budget of a call, like 100ms, to panic on slower calls")
+var slowPerKB = flag.String("slowperkb", "", "time budget of a call per kilobyte of the input, like 10ms, added to the slow one")
+var noautolimits = flag.Bool("noautolimits", false, "do not limit integer arguments which look like allocation sizes, like n or size")
+var pixels = flag.Int("pixels", 0, "pixel budget of images decoded after DecodeConfig, 0 for the default 1048576, negative to decode without checking")
+var testcorpus = flag.Bool("corpus", false, "run the package unit tests to build a corpus")
+var focus = flag.String("focus", "", "function to focus on, like Decode or Reader.Read, with the calls building its arguments")
+var testdata = flag.Bool("testdata", false, "add the files of the package testdata directory to the corpus")
 
 func main() {
 	flag.Parse()
@@ -23,7 +39,48 @@ func main() {
 	} else {
 		log.Printf("Default to outdir in %s", outdir)
 	}
-	err := pkgtofuzzinput.PackageToFuzzer(path, outdir, *exclude, *limits)
+	config := &pkgtofuzzinput.PkgConfig{}
+	if len(*configFile) > 0 {
+		var err error
+		config, err = pkgtofuzzinput.LoadConfig(*configFile)
+		if err != nil {
+			log.Fatalf("Failed loading config : %s", err)
+		}
+	}
+	// command line arguments add up to the config
+	config.Include = append(config.Include, pkgtofuzzinput.SplitList(*include)...)
+	config.Exclude = append(config.Exclude, pkgtofuzzinput.SplitList(*exclude)...)
+	config.Limits = append(config.Limits, pkgtofuzzinput.SplitList(*limits)...)
+	config.Panics = append(config.Panics, pkgtofuzzinput.SplitList(*panics)...)
+	config.Mutable = append(config.Mutable, pkgtofuzzinput.SplitList(*mutable)...)
+	if len(*slow) > 0 {
+		config.Slow = *slow
+	}
+	if len(*slowPerKB) > 0 {
+		config.SlowPerKB = *slowPerKB
+	}
+	if *determinism {
+		config.Determinism = true
+	}
+	if *immutable {
+		config.Immutable = true
+	}
+	if len(*docpanics) > 0 {
+		config.DocPanics = *docpanics
+	}
+	if len(*errorsMode) > 0 {
+		config.Errors = *errorsMode
+	}
+	if *noautolimits {
+		config.NoAutoLimits = true
+	}
+	if *pixels != 0 {
+		config.Pixels = *pixels
+	}
+	if len(*hooks) > 0 {
+		config.Hooks = *hooks
+	}
+	err := pkgtofuzzinput.PackageToFuzzer(path, outdir, config, *testcorpus, *testdata, *focus)
 	if err != nil {
 		log.Fatalf("Failed creating fuzz target : %s", err)
 	}
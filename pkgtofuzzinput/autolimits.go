@@ -0,0 +1,103 @@
+package pkgtofuzzinput
+
+import (
+	"go/ast"
+	"log"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// names of arguments which usually size an allocation
+var autoLimitNames = map[string]bool{
+	"n":      true,
+	"size":   true,
+	"count":  true,
+	"bits":   true,
+	"prec":   true,
+	"length": true,
+}
+
+// integer types wide enough to make a huge allocation
+var autoLimitTypes = map[string]bool{
+	"int": true, "int32": true, "int64": true, "uint": true, "uint32": true, "uint64": true,
+}
+
+// tells if the identifier name is used in the body as a size for make, or as a loop bound
+func usedAsSize(body *ast.BlockStmt, name string) bool {
+	uses := func(e ast.Node) bool {
+		found := false
+		ast.Inspect(e, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == name {
+				found = true
+			}
+			return !found
+		})
+		return found
+	}
+	r := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.CallExpr:
+			if id, ok := v.Fun.(*ast.Ident); ok && id.Name == "make" {
+				for _, a := range v.Args[1:] {
+					r = r || uses(a)
+				}
+			}
+		case *ast.ForStmt:
+			if v.Cond != nil {
+				r = r || uses(v.Cond)
+			}
+		case *ast.RangeStmt:
+			// range over an integer
+			if id, ok := v.X.(*ast.Ident); ok && id.Name == name {
+				r = true
+			}
+		}
+		return !r
+	})
+	return r
+}
+
+// limits the integer arguments which look like allocation sizes, unless they are already limited
+func (c *PkgConfig) addAutoLimits(pkg *packages.Package, descr PkgDescription) error {
+	limitsMap, err := c.limitsMap()
+	if err != nil {
+		return err
+	}
+	bodies := make(map[string]*ast.BlockStmt)
+	for _, f := range pkg.Syntax {
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Body != nil {
+				recv := funcDeclRecv(fd)
+				if len(recv) > 0 {
+					recv = recv + "."
+				}
+				bodies[recv+fd.Name.Name] = fd.Body
+			}
+		}
+	}
+	var auto []string
+	for _, m := range descr.Functions {
+		for a := range m.Args {
+			if m.Args[a].Proto != PkgFuncArgClassProto && m.Args[a].Proto != PkgFuncArgClassProtoGen {
+				continue
+			}
+			if !autoLimitTypes[m.Args[a].FieldType] || m.Args[a].Name == "_" {
+				continue
+			}
+			if _, ok := limitsMap[m.Recv+m.Name+"."+m.Args[a].Name]; ok {
+				continue
+			}
+			body := bodies[QualifiedName(m)]
+			if autoLimitNames[m.Args[a].Name] || (body != nil && usedAsSize(body, m.Args[a].Name)) {
+				auto = append(auto, QualifiedName(m)+"."+m.Args[a].Name)
+			}
+		}
+	}
+	if len(auto) > 0 {
+		log.Printf("Limited automatically, use -noautolimits to disable : %s", strings.Join(auto, ", "))
+		c.Limits = append(c.Limits, auto...)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package pkgtofuzzinput
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// what we know about fuzzing one package, instead of patching the generated code
+type PkgConfig struct {
+	// regular expressions on qualified names like Buffer.Next
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// integer arguments or struct fields to limit, like Regexp.Split.n, GenerateKey.bits=512..4096 or Repeat.count<=1024
+	Limits []string `json:"limits,omitempty"`
+	// types of panic values which are not bugs, like big.ErrNaN for every function
+	// or like Repeat=string for the functions matching a regular expression
+	Panics []string `json:"panics,omitempty"`
+	// what to do with the functions documented to panic : expect, exclude or warn
+	DocPanics string `json:"docpanics,omitempty"`
+	// what to do after a call returned an error : stop, continue or check
+	Errors string `json:"errors,omitempty"`
+	// panic when a function modifies one of its byte slice arguments
+	Immutable bool `json:"immutable,omitempty"`
+	// regular expressions on qualified names of functions allowed to modify their arguments
+	Mutable []string `json:"mutable,omitempty"`
+	// run each input twice, and panic when the results of the calls differ
+	Determinism bool `json:"determinism,omitempty"`
+	// time budget of a call, like 100ms, plus a budget per kilobyte of the input, like 10ms
+	Slow      string `json:"slow,omitempty"`
+	SlowPerKB string `json:"slowperkb,omitempty"`
+	// function building an argument out of its protobuf value, like io.Reader: bytes.NewBuffer
+	Generators map[string]string `json:"generators,omitempty"`
+	// go boolean expression by qualified name, the call is skipped when it is false
+	// arguments are named arg0, arg1... the receiver being arg0
+	Preconditions map[string]string `json:"preconditions,omitempty"`
+	// do not limit the arguments which look like allocation sizes, like n or size
+	NoAutoLimits bool `json:"noautolimits,omitempty"`
+	// pixel budget of images decoded after DecodeConfig, 0 for the default, negative for no guard
+	Pixels int `json:"pixels,omitempty"`
+	// go file with functions like Pre_IntNgdotModSqrt, returning false to skip the call
+	Hooks string `json:"hooks,omitempty"`
+	// hooks found in the go file
+	preHooks map[string]bool
+}
+
+func LoadConfig(path string) (*PkgConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &PkgConfig{}
+	err = json.Unmarshal(data, r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing config %s : %s", path, err)
+	}
+	if len(r.Hooks) > 0 && !filepath.IsAbs(r.Hooks) {
+		// relative to the config file
+		r.Hooks = filepath.Join(filepath.Dir(path), r.Hooks)
+	}
+	return r, nil
+}
+
+// splits a comma-separated command line argument
+func SplitList(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// bounds of a limited integer argument
+type argLimit struct {
+	min int64
+	max int64
+	// without a minimum, signed values lower than the maximum are kept
+	hasMin bool
+}
+
+// default maximum, for limits without bounds like Regexp.Split.n
+const defaultLimit = 0x10000
+
+// types of the arguments which can be limited
+var limitTypes = map[string]bool{
+	"int": true, "int32": true, "int64": true, "rune": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "byte": true,
+}
+
+// expression of type t limiting the value v
+func (l argLimit) expr(t string, v string) string {
+	if !l.hasMin && !strings.HasPrefix(t, "uint") && t != "byte" {
+		return fmt.Sprintf("%s(ngoloLimitMax(int64(%s), %d))", t, v, l.max)
+	}
+	return fmt.Sprintf("%s(ngoloLimit(int64(%s), %d, %d))", t, v, l.min, l.max)
+}
+
+// parses a limit like Regexp.Split.n, GenerateKey.bits=512..4096 or Repeat.count<=1024
+func parseLimit(s string) (string, argLimit, error) {
+	l := argLimit{max: defaultLimit}
+	name := s
+	var err error
+	if i := strings.Index(s, "<="); i >= 0 {
+		name = s[:i]
+		l.max, err = strconv.ParseInt(s[i+2:], 0, 64)
+	} else if i := strings.Index(s, "="); i >= 0 {
+		name = s[:i]
+		bounds := strings.Split(s[i+1:], "..")
+		if len(bounds) != 2 {
+			return "", l, fmt.Errorf("Bad limit %s, expected a range like min..max", s)
+		}
+		l.hasMin = true
+		l.min, err = strconv.ParseInt(bounds[0], 0, 64)
+		if err == nil {
+			l.max, err = strconv.ParseInt(bounds[1], 0, 64)
+		}
+	}
+	if err != nil {
+		return "", l, fmt.Errorf("Bad limit %s : %s", s, err)
+	}
+	if l.max < 0 || (l.hasMin && l.min > l.max) || uint64(l.max-l.min) == math.MaxUint64 {
+		return "", l, fmt.Errorf("Bad limit %s, empty or full range", s)
+	}
+	return name, l, nil
+}
+
+// limits are either like Regexp.Split.n or RegexpNgdotSplit.n, or Struct.Field for fields
+func (c *PkgConfig) limitsMap() (map[string]argLimit, error) {
+	r := make(map[string]argLimit, len(c.Limits))
+	for _, s := range c.Limits {
+		name, l, err := parseLimit(s)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(name, ".")
+		if len(parts) == 3 {
+			name = parts[0] + "Ngdot" + parts[1] + "." + parts[2]
+		}
+		r[name] = l
+	}
+	return r, nil
+}
+
+func (c *PkgConfig) pixels() int {
+	if c.Pixels == 0 {
+		return defaultPixels
+	}
+	return c.Pixels
+}
+
+// overrides how the fuzz target and the reproducer build these arguments
+func (c *PkgConfig) applyGenerators() error {
+	for t, g := range c.Generators {
+		if _, ok := ProtoGenerated[t]; !ok {
+			return fmt.Errorf("Generator for %s which is not generated from protobuf", t)
+		}
+		ProtoGenerators[t] = g
+		var imports []string
+		if i := strings.LastIndex(g, "."); i > 0 {
+			imports = append(imports, g[:i])
+		}
+		ProtoGeneratorsRepro[t] = ProtoGeneratorRepro{g + "(%#+v)", "", imports}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package pkgtofuzzinput
+
+import "testing"
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		s     string
+		name  string
+		limit argLimit
+		err   bool
+	}{
+		{"Regexp.Split.n", "Regexp.Split.n", argLimit{max: defaultLimit}, false},
+		{"GenerateKey.bits=512..4096", "GenerateKey.bits", argLimit{min: 512, max: 4096, hasMin: true}, false},
+		{"Repeat.count<=1024", "Repeat.count", argLimit{max: 1024}, false},
+		{"Rect.x1=-10..10", "Rect.x1", argLimit{min: -10, max: 10, hasMin: true}, false},
+		{"NewWriter.padding<=0x100", "NewWriter.padding", argLimit{max: 0x100}, false},
+		{"GenerateKey.bits=4096..512", "", argLimit{}, true},
+		{"GenerateKey.bits=512", "", argLimit{}, true},
+		{"GenerateKey.bits=a..b", "", argLimit{}, true},
+		{"Repeat.count<=-1", "", argLimit{}, true},
+	}
+	for _, tt := range tests {
+		name, l, err := parseLimit(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("parseLimit(%q) error = %v, want error %v", tt.s, err, tt.err)
+			continue
+		}
+		if !tt.err && (name != tt.name || l != tt.limit) {
+			t.Errorf("parseLimit(%q) = %s, %+v, want %s, %+v", tt.s, name, l, tt.name, tt.limit)
+		}
+	}
+}
+
+func TestLimitsMap(t *testing.T) {
+	c := &PkgConfig{Limits: []string{"Regexp.Split.n", "RegexpNgdotFindAll.n<=10", "Rectangle.Max", "New.n=1..2"}}
+	m, err := c.limitsMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"RegexpNgdotSplit.n", "RegexpNgdotFindAll.n", "Rectangle.Max", "New.n"} {
+		if _, ok := m[name]; !ok {
+			t.Errorf("limitsMap() has no %s : %v", name, m)
+		}
+	}
+}
+
+func TestArgLimitExpr(t *testing.T) {
+	tests := []struct {
+		l    argLimit
+		t    string
+		want string
+	}{
+		{argLimit{max: 1024}, "int", "int(ngoloLimitMax(int64(v), 1024))"},
+		{argLimit{max: 1024}, "uint", "uint(ngoloLimit(int64(v), 0, 1024))"},
+		{argLimit{max: 1024}, "byte", "byte(ngoloLimit(int64(v), 0, 1024))"},
+		{argLimit{min: 512, max: 4096, hasMin: true}, "int", "int(ngoloLimit(int64(v), 512, 4096))"},
+	}
+	for _, tt := range tests {
+		if got := tt.l.expr(tt.t, "v"); got != tt.want {
+			t.Errorf("%+v.expr(%s) = %s, want %s", tt.l, tt.t, got, tt.want)
+		}
+	}
+}
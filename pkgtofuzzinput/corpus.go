@@ -0,0 +1,768 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// added to the instrumented copy of the package
+const corpusHelper = `package %s
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// avoids filling the corpus with the calls made in a loop
+const ngoloCorpusMaxPerFunction = 1024
+
+// limits the calls made on one object before a call in a sequence
+const ngoloCorpusMaxUsers = 16
+const ngoloCorpusMaxSequence = 256
+
+// object used or produced by a call, identified by its pointer, nil when unknown
+type ngoloCorpusObject struct {
+	name string
+	ptr  interface{}
+}
+
+type ngoloCorpusCall struct {
+	index   int
+	data    []byte
+	kind    reflect.Type
+	args    []ngoloCorpusObject
+	results []ngoloCorpusObject
+	// called by the tests, with known objects as arguments
+	tracked bool
+}
+
+type ngoloCorpusKey struct {
+	kind     reflect.Type
+	sequence bool
+}
+
+var ngoloCorpusMutex sync.Mutex
+var ngoloCorpusSeen = make(map[[sha1.Size]byte]bool)
+var ngoloCorpusCounts = make(map[ngoloCorpusKey]int)
+// calls in progress by goroutine, as the tests may run in parallel
+var ngoloCorpusDepth = make(map[uint64]int)
+var ngoloCorpusIndex int
+var ngoloCorpusProducers = make(map[interface{}]*ngoloCorpusCall)
+var ngoloCorpusUsers = make(map[interface{}][]*ngoloCorpusCall)
+
+// id of the current goroutine, out of the first line of its stack like "goroutine 7 [running]:"
+func ngoloCorpusGoroutine() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(fields[1], 10, 64)
+	return id
+}
+
+func ngoloCorpusWrite(dir string, data []byte, key ngoloCorpusKey) {
+	hash := sha1.Sum(data)
+	if ngoloCorpusSeen[hash] || ngoloCorpusCounts[key] >= ngoloCorpusMaxPerFunction {
+		return
+	}
+	ngoloCorpusSeen[hash] = true
+	ngoloCorpusCounts[key]++
+	fname := filepath.Join(dir, string(hex.EncodeToString(hash[:])))
+	os.WriteFile(fname, data, 0644)
+}
+
+func ngoloCorpusIsNil(p interface{}) bool {
+	if p == nil {
+		return true
+	}
+	v := reflect.ValueOf(p)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+func NgoloCorpusArg(name string, p interface{}) ngoloCorpusObject {
+	if ngoloCorpusIsNil(p) {
+		return ngoloCorpusObject{name, nil}
+	}
+	return ngoloCorpusObject{name, p}
+}
+
+// the fuzz target does not keep nil pointers
+func NgoloCorpusPointer(name string, p interface{}) []ngoloCorpusObject {
+	if ngoloCorpusIsNil(p) {
+		return nil
+	}
+	return []ngoloCorpusObject{{name, p}}
+}
+
+// the fuzz target keeps a pointer to a copy of the value
+func NgoloCorpusValue(name string) []ngoloCorpusObject {
+	return []ngoloCorpusObject{{name, nil}}
+}
+
+func NgoloCorpusSlice(name string, s interface{}) []ngoloCorpusObject {
+	v := reflect.ValueOf(s)
+	r := make([]ngoloCorpusObject, v.Len())
+	for i := range r {
+		r[i] = NgoloCorpusArg(name, v.Index(i).Interface())
+	}
+	return r
+}
+
+// previous calls producing and using the objects needed by the call
+func ngoloCorpusSequence(c *ngoloCorpusCall) []*ngoloCorpusCall {
+	calls := make(map[*ngoloCorpusCall]bool)
+	todo := c.args
+	for len(todo) > 0 {
+		o := todo[0]
+		todo = todo[1:]
+		producer, ok := ngoloCorpusProducers[o.ptr]
+		if !ok {
+			return nil
+		}
+		for _, u := range append([]*ngoloCorpusCall{producer}, ngoloCorpusUsers[o.ptr]...) {
+			if !calls[u] {
+				calls[u] = true
+				todo = append(todo, u.args...)
+			}
+		}
+		if len(calls) > ngoloCorpusMaxSequence {
+			return nil
+		}
+	}
+	r := make([]*ngoloCorpusCall, 0, len(calls)+1)
+	for u := range calls {
+		r = append(r, u)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].index < r[j].index
+	})
+	return append(r, c)
+}
+
+// checks that the fuzz target takes the same objects out of its pools
+func ngoloCorpusReplays(seq []*ngoloCorpusCall) bool {
+	pools := make(map[string][]interface{})
+	indexes := make(map[string]int)
+	for _, c := range seq {
+		for _, a := range c.args {
+			pool := pools[a.name]
+			if len(pool) == 0 || pool[indexes[a.name]] != a.ptr {
+				return false
+			}
+			indexes[a.name] = (indexes[a.name] + 1) %% len(pool)
+		}
+		for _, r := range c.results {
+			pools[r.name] = append(pools[r.name], r.ptr)
+		}
+	}
+	return true
+}
+
+// records a call before it is made, and generates corpus files out of it
+// one with the single call, and one with the calls needed to build its arguments
+func NgoloCorpusCall(item interface{}, args ...ngoloCorpusObject) *ngoloCorpusCall {
+	// use a directory specified by an environment variable
+	dir := os.Getenv("FUZZ_NG_CORPUS_DIR")
+	if len(dir) == 0 {
+		return nil
+	}
+	ngolo_list := &NgoloFuzzOne{Item: item.(isNgoloFuzzOne_Item)}
+	ngolo_fuzz := NgoloFuzzList{List: []*NgoloFuzzOne{ngolo_list}}
+	// arguments like strings with invalid utf-8 cannot be encoded, and make no seeds
+	data, err := proto.Marshal(&ngolo_fuzz)
+	c := &ngoloCorpusCall{data: data, kind: reflect.TypeOf(item), args: args}
+
+	goroutine := ngoloCorpusGoroutine()
+
+	ngoloCorpusMutex.Lock()
+	defer ngoloCorpusMutex.Unlock()
+	if err == nil {
+		ngoloCorpusWrite(dir, data, ngoloCorpusKey{c.kind, false})
+	}
+	// calls made by other functions of the package are not tracked
+	c.tracked = ngoloCorpusDepth[goroutine] == 0 && err == nil
+	ngoloCorpusDepth[goroutine]++
+	ngoloCorpusIndex++
+	c.index = ngoloCorpusIndex
+	for _, a := range args {
+		if a.ptr == nil {
+			c.tracked = false
+		}
+	}
+	if c.tracked && len(args) > 0 {
+		seq := ngoloCorpusSequence(c)
+		if seq != nil && ngoloCorpusReplays(seq) {
+			// serialized lists get merged by concatenation
+			var seqdata []byte
+			for _, s := range seq {
+				seqdata = append(seqdata, s.data...)
+			}
+			ngoloCorpusWrite(dir, seqdata, ngoloCorpusKey{c.kind, true})
+		}
+	}
+	return c
+}
+
+// records the objects produced by a call, once it returned
+// calls failing would stop the fuzz target, so they are not used in sequences
+func NgoloCorpusReturn(c *ngoloCorpusCall, panicked interface{}, err error, results ...[]ngoloCorpusObject) {
+	if c != nil {
+		goroutine := ngoloCorpusGoroutine()
+		ngoloCorpusMutex.Lock()
+		if ngoloCorpusDepth[goroutine]--; ngoloCorpusDepth[goroutine] == 0 {
+			delete(ngoloCorpusDepth, goroutine)
+		}
+		if c.tracked && panicked == nil && err == nil {
+			for _, r := range results {
+				c.results = append(c.results, r...)
+			}
+			for _, r := range c.results {
+				if _, ok := ngoloCorpusProducers[r.ptr]; r.ptr != nil && !ok {
+					ngoloCorpusProducers[r.ptr] = c
+				}
+			}
+			for _, a := range c.args {
+				users := ngoloCorpusUsers[a.ptr]
+				if len(users) >= ngoloCorpusMaxUsers {
+					users = users[1:]
+				}
+				ngoloCorpusUsers[a.ptr] = append(users, c)
+			}
+		}
+		ngoloCorpusMutex.Unlock()
+	}
+	if panicked != nil {
+		panic(panicked)
+	}
+}
+
+// readers are read up to this size, the rest is kept for the function
+const ngoloCorpusMaxRead = 0x100000
+
+func NgoloCorpusReader(r io.Reader) ([]byte, io.Reader) {
+	if r == nil {
+		return nil, r
+	}
+	data, _ := io.ReadAll(io.LimitReader(r, ngoloCorpusMaxRead))
+	if len(data) < ngoloCorpusMaxRead {
+		return data, bytes.NewReader(data)
+	}
+	return data, io.MultiReader(bytes.NewReader(data), r)
+}
+
+func NgoloCorpusBufioReader(r *bufio.Reader) ([]byte, *bufio.Reader) {
+	if r == nil {
+		return nil, r
+	}
+	data, rest := NgoloCorpusReader(r)
+	return data, bufio.NewReader(rest)
+}
+
+type ngoloCorpusRuneReader struct {
+	head *strings.Reader
+	tail io.RuneReader
+}
+
+func (r *ngoloCorpusRuneReader) ReadRune() (rune, int, error) {
+	if r.head.Len() > 0 {
+		return r.head.ReadRune()
+	}
+	return r.tail.ReadRune()
+}
+
+func NgoloCorpusRuneReader(r io.RuneReader) (string, io.RuneReader) {
+	if r == nil {
+		return "", r
+	}
+	var b strings.Builder
+	for b.Len() < ngoloCorpusMaxRead {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return b.String(), strings.NewReader(b.String())
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), &ngoloCorpusRuneReader{strings.NewReader(b.String()), r}
+}
+
+func NgoloCorpusReaderAt(r io.ReaderAt) []byte {
+	if r == nil {
+		return nil
+	}
+	data, _ := io.ReadAll(io.NewSectionReader(r, 0, ngoloCorpusMaxRead))
+	return data
+}
+
+func NgoloCorpusIntArray(a []int) []int64 {
+	r := make([]int64, len(a))
+	for i := range a {
+		r[i] = int64(a[i])
+	}
+	return r
+}
+
+func NgoloCorpusUint16Array(a []uint16) []int64 {
+	r := make([]int64, len(a))
+	for i := range a {
+		r[i] = int64(a[i])
+	}
+	return r
+}
+
+func NgoloCorpusAny(v interface{}) *NgoloFuzzAny {
+	switch x := v.(type) {
+	case float64:
+		return &NgoloFuzzAny{Item: &NgoloFuzzAny_DoubleArgs{DoubleArgs: x}}
+	case int64:
+		return &NgoloFuzzAny{Item: &NgoloFuzzAny_Int64Args{Int64Args: x}}
+	case int:
+		return &NgoloFuzzAny{Item: &NgoloFuzzAny_Int64Args{Int64Args: int64(x)}}
+	case bool:
+		return &NgoloFuzzAny{Item: &NgoloFuzzAny_BoolArgs{BoolArgs: x}}
+	case string:
+		return &NgoloFuzzAny{Item: &NgoloFuzzAny_StringArgs{StringArgs: x}}
+	case []byte:
+		return &NgoloFuzzAny{Item: &NgoloFuzzAny_BytesArgs{BytesArgs: x}}
+	}
+	return nil
+}
+
+func NgoloCorpusBigInt(x *big.Int) *NgoloFuzzBigInt {
+	if x == nil {
+		return nil
+	}
+	return &NgoloFuzzBigInt{Neg: x.Sign() < 0, Abs: x.Bytes()}
+}
+
+func NgoloCorpusBigFloat(x *big.Float) *NgoloFuzzBigFloat {
+	if x == nil {
+		return nil
+	}
+	r := &NgoloFuzzBigFloat{Neg: x.Signbit(), Prec: uint32(x.Prec()), Mode: uint32(x.Mode())}
+	switch {
+	case x.IsInf():
+		r.Kind = NgoloFuzzBigFloatKind_BigFloatInf
+	case x.Sign() == 0:
+		r.Kind = NgoloFuzzBigFloatKind_BigFloatZero
+	default:
+		// the mantissa is in [0.5, 1), scale it to an integer
+		mant := new(big.Float)
+		exp := x.MantExp(mant)
+		prec := int(x.MinPrec())
+		m, _ := mant.SetMantExp(mant, prec).Int(nil)
+		r.Mant = m.Bytes()
+		r.Exp = int32(exp - prec)
+	}
+	return r
+}
+
+func NgoloCorpusBigRat(x *big.Rat) *NgoloFuzzBigRat {
+	if x == nil {
+		return nil
+	}
+	return &NgoloFuzzBigRat{Num: NgoloCorpusBigInt(x.Num()), Denom: NgoloCorpusBigInt(x.Denom())}
+}
+`
+
+// helpers reading an argument, and returning a reader with the same content if needed
+var corpusReaders = map[string]string{
+	"io.Reader":     "NgoloCorpusReader",
+	"bufio.Reader":  "NgoloCorpusBufioReader",
+	"io.RuneReader": "NgoloCorpusRuneReader",
+	"io.ReaderAt":   "NgoloCorpusReaderAt",
+}
+
+// returns the function converting a golang value into its protobuf field
+// empty string means no conversion is needed, and false means it is not handled
+func corpusConverter(arg PkgFuncArg) (string, bool) {
+	switch arg.Proto {
+	case PkgFuncArgClassProto:
+		return "", true
+	case PkgFuncArgClassProtoGen:
+		switch arg.FieldType {
+		case "int":
+			return "int64", true
+		case "rune":
+			return "string", true
+		case "byte", "uint", "uint8", "uint16":
+			return "uint32", true
+		case "[]int":
+			return "NgoloCorpusIntArray", true
+		case "[]uint16":
+			return "NgoloCorpusUint16Array", true
+		case "any":
+			return "NgoloCorpusAny", true
+		case "big.Int", "big.Float", "big.Rat":
+			return "NgoloCorpusBig" + arg.FieldType[len("big."):], true
+		}
+	case PkgFuncArgClassPkgConst:
+		if strings.HasPrefix(arg.FieldType, "repeated ") {
+			return "NgoloCorpusConvert" + arg.FieldType[len("repeated "):], true
+		}
+		return "NgoloCorpus" + arg.FieldType, true
+	case PkgFuncArgClassPkgStruct:
+		return "NgoloCorpus" + arg.FieldType + "Struct", true
+	}
+	return "", false
+}
+
+// writes the helpers converting package types into their protobuf messages
+func PackageToCorpusHelper(descr PkgDescription, w io.StringWriter, pkgname string) error {
+	w.WriteString(fmt.Sprintf(corpusHelper, pkgname))
+	for _, r := range descr.Types {
+		if len(r.Values) > 0 {
+			w.WriteString(fmt.Sprintf("\nfunc NgoloCorpus%s(v %s) %sEnum {\n", r.Name, r.Name, r.Name))
+			// no switch as constants may have the same value
+			for i := 1; i < len(r.Values); i++ {
+				w.WriteString(fmt.Sprintf("\tif v == %s {\n", r.Values[i]))
+				w.WriteString(fmt.Sprintf("\t\treturn %d\n", i))
+				w.WriteString("\t}\n")
+			}
+			w.WriteString("\treturn 0\n")
+			w.WriteString("}\n")
+			w.WriteString(fmt.Sprintf("\nfunc NgoloCorpusConvert%s(a []%s) []%sEnum {\n", r.Name, r.Name, r.Name))
+			w.WriteString(fmt.Sprintf("\tr := make([]%sEnum, len(a))\n", r.Name))
+			w.WriteString("\tfor i := range a {\n")
+			w.WriteString(fmt.Sprintf("\t\tr[i] = NgoloCorpus%s(a[i])\n", r.Name))
+			w.WriteString("\t}\n")
+			w.WriteString("\treturn r\n")
+			w.WriteString("}\n")
+		} else if len(r.Args) > 0 {
+			w.WriteString(fmt.Sprintf("\nfunc NgoloCorpus%sStruct(p *%s) *%sStruct {\n", r.Name, r.Name, r.Name))
+			w.WriteString("\tif p == nil {\n")
+			w.WriteString("\t\treturn nil\n")
+			w.WriteString("\t}\n")
+			w.WriteString(fmt.Sprintf("\treturn &%sStruct{\n", r.Name))
+			for _, a := range r.Args {
+				conv, ok := corpusConverter(a)
+				if !ok {
+					continue
+				}
+				w.WriteString(fmt.Sprintf("\t\t%s%s: %s(p.%s),\n", TitleCase(a.Name), a.Suffix, conv, a.Name))
+			}
+			w.WriteString("\t}\n")
+			w.WriteString("}\n")
+		}
+	}
+	return nil
+}
+
+// statements recording a call to the function, to be inserted at the top of its body
+// results are the names of the function results
+func corpusRecordStmts(m PkgFunction, f *ast.FuncDecl, results []string) []ast.Stmt {
+	// position of the opening brace so that comments get printed after
+	pos := f.Body.Lbrace
+	ident := func(name string) *ast.Ident {
+		return &ast.Ident{NamePos: pos, Name: name}
+	}
+	call := func(fun string, args ...ast.Expr) *ast.CallExpr {
+		return &ast.CallExpr{Fun: ident(fun), Lparen: pos, Args: args, Rparen: pos}
+	}
+	addr := func(x ast.Expr) *ast.UnaryExpr {
+		return &ast.UnaryExpr{OpPos: pos, Op: token.AND, X: x}
+	}
+	str := func(s string) *ast.BasicLit {
+		return &ast.BasicLit{ValuePos: pos, Kind: token.STRING, Value: fmt.Sprintf("%q", s)}
+	}
+	var stmts []ast.Stmt
+	var fields []ast.Expr
+	// objects taken out of the pools by the fuzz target
+	var pooled []ast.Expr
+	for a, arg := range m.Args {
+		if arg.Proto == PkgFuncArgClassPkgGen {
+			var p ast.Expr = ident(arg.Name)
+			if arg.Name == "_" || arg.Prefix != "" {
+				// cannot know which object the value comes from
+				p = ident("nil")
+			} else if a == 0 && f.Recv != nil {
+				if _, ok := f.Recv.List[0].Type.(*ast.StarExpr); !ok {
+					p = ident("nil")
+				}
+			}
+			pooled = append(pooled, call("NgoloCorpusArg", str(arg.FieldType), p))
+			continue
+		}
+		if arg.Name == "_" {
+			continue
+		}
+		var value ast.Expr
+		reader, isReader := corpusReaders[arg.FieldType]
+		if arg.Proto == PkgFuncArgClassProtoGen && isReader {
+			lhs := []ast.Expr{ident("ngolo_" + arg.Name)}
+			if arg.FieldType != "io.ReaderAt" {
+				// the function reads the same content out of a new reader
+				lhs = append(lhs, ident(arg.Name))
+			}
+			stmts = append(stmts, &ast.AssignStmt{Lhs: lhs, TokPos: pos, Tok: token.DEFINE, Rhs: []ast.Expr{call(reader, ident(arg.Name))}})
+			value = ident("ngolo_" + arg.Name)
+		} else {
+			conv, ok := corpusConverter(arg)
+			if !ok {
+				continue
+			}
+			value = ident(arg.Name)
+			if arg.Proto == PkgFuncArgClassPkgStruct && a == 0 && f.Recv != nil {
+				if _, ok := f.Recv.List[0].Type.(*ast.StarExpr); !ok {
+					value = addr(value)
+				}
+			}
+			if len(conv) > 0 {
+				value = call(conv, value)
+			}
+		}
+		fields = append(fields, &ast.KeyValueExpr{Key: ident(TitleCase(arg.Name)), Colon: pos, Value: value})
+	}
+	item := fmt.Sprintf("%s%s%s", m.Recv, CamelCase(m.Name), m.Suffix)
+	args := &ast.CompositeLit{Type: ident(fmt.Sprintf("%s%sArgs", m.Recv, CamelCase(m.Name))), Lbrace: pos, Elts: fields, Rbrace: pos}
+	one := &ast.CompositeLit{Type: ident("NgoloFuzzOne_" + item), Lbrace: pos, Elts: []ast.Expr{
+		&ast.KeyValueExpr{Key: ident(item), Colon: pos, Value: addr(args)},
+	}, Rbrace: pos}
+	stmts = append(stmts, &ast.AssignStmt{Lhs: []ast.Expr{ident("ngolo_call")}, TokPos: pos, Tok: token.DEFINE,
+		Rhs: []ast.Expr{call("NgoloCorpusCall", append([]ast.Expr{addr(one)}, pooled...)...)}})
+
+	// objects added to the pools by the fuzz target
+	var errResult ast.Expr = ident("nil")
+	var produced []ast.Expr
+	for r, ret := range m.Returns {
+		if !ret.Used {
+			continue
+		}
+		switch {
+		case ret.FieldType == "error":
+			if errResult.(*ast.Ident).Name == "nil" {
+				errResult = ident(results[r])
+			}
+		case ret.Suffix == "...":
+			produced = append(produced, call("NgoloCorpusSlice", str(ret.FieldType), ident(results[r])))
+		case ret.Prefix == "&":
+			produced = append(produced, call("NgoloCorpusValue", str(ret.FieldType)))
+		default:
+			produced = append(produced, call("NgoloCorpusPointer", str(ret.FieldType), ident(results[r])))
+		}
+	}
+	ret := call("NgoloCorpusReturn", append([]ast.Expr{ident("ngolo_call"), call("recover"), errResult}, produced...)...)
+	deferred := &ast.FuncLit{
+		Type: &ast.FuncType{Func: pos, Params: &ast.FieldList{Opening: pos, Closing: pos}},
+		Body: &ast.BlockStmt{Lbrace: pos, List: []ast.Stmt{&ast.ExprStmt{X: ret}}, Rbrace: pos},
+	}
+	return append(stmts, &ast.DeferStmt{Defer: pos, Call: &ast.CallExpr{Fun: deferred, Lparen: pos, Rparen: pos}})
+}
+
+// names the results of the function if they are needed, and returns their names
+// results get unnamed back by restore
+func corpusNameResults(m PkgFunction, f *ast.FuncDecl) (names []string, restore func()) {
+	restore = func() {}
+	if f.Type.Results == nil {
+		return nil, restore
+	}
+	needed := false
+	for _, r := range m.Returns {
+		if r.Used {
+			needed = true
+		}
+	}
+	saved := make([][]*ast.Ident, len(f.Type.Results.List))
+	for i, field := range f.Type.Results.List {
+		saved[i] = field.Names
+		if len(field.Names) == 0 {
+			if needed {
+				field.Names = []*ast.Ident{{NamePos: field.Pos(), Name: fmt.Sprintf("ngolo_r%d", len(names))}}
+			} else {
+				names = append(names, "_")
+				continue
+			}
+		}
+		for n := range field.Names {
+			if field.Names[n].Name == "_" && needed {
+				if len(field.Names) == 1 {
+					field.Names = []*ast.Ident{{NamePos: field.Pos(), Name: fmt.Sprintf("ngolo_r%d", len(names))}}
+				} else {
+					field.Names = append([]*ast.Ident{}, field.Names...)
+					field.Names[n] = &ast.Ident{NamePos: field.Names[n].Pos(), Name: fmt.Sprintf("ngolo_r%d", len(names))}
+				}
+			}
+			names = append(names, field.Names[n].Name)
+		}
+	}
+	restore = func() {
+		for i, field := range f.Type.Results.List {
+			field.Names = saved[i]
+		}
+	}
+	return names, restore
+}
+
+// writes a copy of the package where every function of the description records its calls
+func PackageToCorpus(pkg *packages.Package, descr PkgDescription, outdir string) error {
+	functions := make(map[string]PkgFunction, len(descr.Functions))
+	for _, m := range descr.Functions {
+		functions[QualifiedName(m)] = m
+	}
+	for s := range pkg.Syntax {
+		// instrument the syntax tree, and restore it once printed
+		var restores []func()
+		for _, d := range pkg.Syntax[s].Decls {
+			f, ok := d.(*ast.FuncDecl)
+			if !ok || f.Body == nil || f.Type.TypeParams != nil {
+				continue
+			}
+			name := f.Name.Name
+			if f.Recv != nil && len(f.Recv.List) == 1 {
+				recv, ok := astGetName(f.Recv.List[0].Type)
+				if !ok {
+					continue
+				}
+				name = recv + "." + name
+			}
+			m, ok := functions[name]
+			if !ok {
+				continue
+			}
+			results, restore := corpusNameResults(m, f)
+			if len(results) != len(m.Returns) {
+				restore()
+				continue
+			}
+			body := f.Body.List
+			f.Body.List = append(corpusRecordStmts(m, f, results), body...)
+			restores = append(restores, func() {
+				f.Body.List = body
+				restore()
+			})
+		}
+
+		fcopy, err := os.Create(filepath.Join(outdir, filepath.Base(pkg.CompiledGoFiles[s])))
+		if err != nil {
+			log.Printf("Failed creating file : %s", err)
+			return err
+		}
+		cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+		err = cfg.Fprint(fcopy, pkg.Fset, pkg.Syntax[s])
+		fcopy.Close()
+		for _, restore := range restores {
+			restore()
+		}
+		if err != nil {
+			log.Printf("Failed printing file : %s", err)
+			return err
+		}
+	}
+
+	f, err := os.Create(filepath.Join(outdir, "ngolo_helper.go"))
+	if err != nil {
+		log.Printf("Failed creating file : %s", err)
+		return err
+	}
+	defer f.Close()
+	return PackageToCorpusHelper(descr, f, pkg.Syntax[0].Name.Name)
+}
+
+// runs the unit tests of the instrumented copy of the package to fill the corpus directory
+func CorpusFromTests(pkg *packages.Package, descr PkgDescription, copydir string, cdir string) error {
+	pkgdir := filepath.Dir(pkg.GoFiles[0])
+	pkgname := pkg.Syntax[0].Name.Name
+
+	// same protobuf messages, but in the package of the copy
+	f, err := os.Create(filepath.Join(copydir, "ngolofuzz.proto"))
+	if err != nil {
+		log.Printf("Failed creating file : %s", err)
+		return err
+	}
+	err = PackageToProtobuf(pkg, descr, f, pkgname)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("protoc", "--go_out=./", "ngolofuzz.proto")
+	cmd.Dir = copydir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		log.Printf("Failed running protoc : %s", err)
+		return err
+	}
+
+	copies, err := packages.Load(&packages.Config{Mode: packages.NeedName, Dir: copydir}, ".")
+	if err != nil || len(copies) != 1 {
+		log.Printf("Failed loading copy of package : %s", err)
+		return fmt.Errorf("Failed loading copy of package")
+	}
+	tests, err := filepath.Glob(filepath.Join(pkgdir, "*_test.go"))
+	if err != nil {
+		return err
+	}
+	for _, t := range tests {
+		data, err := os.ReadFile(t)
+		if err != nil {
+			log.Printf("Failed reading file : %s", err)
+			return err
+		}
+		// external tests must use the copy
+		src := strings.Replace(string(data), fmt.Sprintf("%q", pkg.ID), fmt.Sprintf("%q", copies[0].PkgPath), -1)
+		err = os.WriteFile(filepath.Join(copydir, filepath.Base(t)), []byte(src), 0644)
+		if err != nil {
+			log.Printf("Failed writing file : %s", err)
+			return err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(pkgdir, "testdata")); err == nil {
+		os.Remove(filepath.Join(copydir, "testdata"))
+		err = os.Symlink(filepath.Join(pkgdir, "testdata"), filepath.Join(copydir, "testdata"))
+		if err != nil {
+			log.Printf("Failed linking testdata : %s", err)
+		}
+	}
+
+	abscdir, err := filepath.Abs(cdir)
+	if err != nil {
+		return err
+	}
+	cmd = exec.Command("go", "test", "-count=1", "-vet=off", ".")
+	cmd.Dir = copydir
+	cmd.Env = append(os.Environ(), "FUZZ_NG_CORPUS_DIR="+abscdir)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		// seeds got written even if some tests failed
+		log.Printf("Failed running unit tests : %s", err)
+	}
+
+	seeds, err := os.ReadDir(cdir)
+	if err != nil {
+		return err
+	}
+	log.Printf("Corpus has %d inputs in %s", len(seeds), cdir)
+	return nil
+}
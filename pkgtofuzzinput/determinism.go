@@ -0,0 +1,91 @@
+package pkgtofuzzinput
+
+// imports of the determinism oracle
+var determinismImports = []string{"encoding", "hash/fnv", "reflect", "regexp"}
+
+// runs each input twice, and compares the results of the calls
+const fuzzTargetDeterminism = `
+// panic value of inputs giving different results when run twice
+type ngoloNondeterministic string
+
+type ngoloResult struct {
+	name  string
+	hash  uint64
+	value string
+}
+
+// results of the calls of the current run
+var ngoloResults *[]ngoloResult
+
+var ngoloPointer = regexp.MustCompile("\\)\\(0x[0-9a-f]+\\)")
+
+// describes a result the same way from one run to the next, without pointer values
+func ngoloResultString(v interface{}) string {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return "nil"
+	}
+	switch x := v.(type) {
+	case error:
+		return x.Error()
+	case encoding.BinaryMarshaler:
+		if b, err := x.MarshalBinary(); err == nil {
+			return fmt.Sprintf("%#v", b)
+		}
+	case encoding.TextMarshaler:
+		if b, err := x.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return ngoloPointer.ReplaceAllString(fmt.Sprintf("%#v", v), ")(ptr)")
+}
+
+func ngoloRecord(name string, results ...interface{}) {
+	if ngoloResults == nil {
+		return
+	}
+	values := make([]string, len(results))
+	for i := range results {
+		values[i] = ngoloResultString(results[i])
+	}
+	value := strings.Join(values, ", ")
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	if len(value) > 256 {
+		value = value[:256] + "..."
+	}
+	*ngoloResults = append(*ngoloResults, ngoloResult{name, h.Sum64(), value})
+}
+
+// runs the input twice, on fresh pools, and panics at the first call whose results differ
+func ngoloRun(gen *NgoloFuzzList) int {
+	first := make([]ngoloResult, 0)
+	ngoloResults = &first
+	FuzzNG_List(gen)
+	second := make([]ngoloResult, 0)
+	ngoloResults = &second
+	r := FuzzNG_List(gen)
+	ngoloResults = nil
+	for i := range first {
+		if i >= len(second) {
+			panic(ngoloNondeterministic(fmt.Sprintf("call %d to %s was not made again", i, first[i].name)))
+		}
+		if first[i].name != second[i].name {
+			panic(ngoloNondeterministic(fmt.Sprintf("call %d to %s was a call to %s", i, first[i].name, second[i].name)))
+		}
+		if first[i].hash != second[i].hash {
+			panic(ngoloNondeterministic(fmt.Sprintf("call %d to %s returned %s then %s", i, first[i].name, first[i].value, second[i].value)))
+		}
+	}
+	if len(second) > len(first) {
+		panic(ngoloNondeterministic(fmt.Sprintf("call %d to %s was not made the first time", len(first), second[len(first)].name)))
+	}
+	return r
+}
+`
+
+// runs each input once
+const fuzzTargetRunOnce = `
+func ngoloRun(gen *NgoloFuzzList) int {
+	return FuzzNG_List(gen)
+}
+`
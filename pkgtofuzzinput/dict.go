@@ -0,0 +1,187 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// libFuzzer ignores longer entries
+const dictMaxEntry = 64
+
+// calls whose literals are messages, not magic values
+var dictSkippedCalls = map[string]bool{
+	"errors.New": true,
+	"fmt.Errorf": true,
+	"panic":      true,
+}
+
+// escapes an entry in the libFuzzer dictionary syntax
+func dictEscape(s string) string {
+	var b strings.Builder
+	b.WriteString("\"")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' || c == '"':
+			b.WriteString("\\")
+			b.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			b.WriteString(fmt.Sprintf("\\x%02X", c))
+		}
+	}
+	b.WriteString("\"")
+	return b.String()
+}
+
+type dictCollector struct {
+	e       *seedExtractor
+	entries map[string]bool
+	// bytes compared one by one, like data[0] == 'B' && data[1] == 'U'
+	indexed map[string]map[int64]byte
+}
+
+// records a comparison of an indexed byte with a constant
+func (d *dictCollector) addIndexed(x ast.Expr, y ast.Expr) {
+	ie, ok := x.(*ast.IndexExpr)
+	if !ok {
+		return
+	}
+	i, ok := constant.Int64Val(constant.ToInt(d.e.eval(ie.Index, nil, 0)))
+	if !ok || i < 0 || i > dictMaxEntry {
+		return
+	}
+	c, ok := constant.Uint64Val(constant.ToInt(d.e.eval(y, nil, 0)))
+	if !ok || c > 0xFF {
+		return
+	}
+	name := types.ExprString(ie.X)
+	if d.indexed[name] == nil {
+		d.indexed[name] = make(map[int64]byte)
+	}
+	if _, ok := d.indexed[name][i]; !ok {
+		d.indexed[name][i] = byte(c)
+	}
+}
+
+// adds the runs of consecutive indexed bytes
+func (d *dictCollector) flushIndexed() {
+	for _, bytes := range d.indexed {
+		var run []byte
+		for i := int64(0); i <= dictMaxEntry+1; i++ {
+			c, ok := bytes[i]
+			if ok {
+				run = append(run, c)
+				continue
+			}
+			d.addEntry(string(run))
+			run = nil
+		}
+	}
+	d.indexed = make(map[string]map[int64]byte)
+}
+
+func (d *dictCollector) add(v constant.Value) {
+	switch v.Kind() {
+	case constant.String:
+		d.addEntry(constant.StringVal(v))
+	case constant.Int:
+		// integers are varints in the protobuf input
+		if x, ok := constant.Int64Val(v); ok {
+			d.addEntry(string(protowire.AppendVarint(nil, uint64(x))))
+		} else if x, ok := constant.Uint64Val(v); ok {
+			d.addEntry(string(protowire.AppendVarint(nil, x)))
+		}
+	}
+}
+
+func (d *dictCollector) addEntry(s string) {
+	// single bytes get found without help
+	if len(s) < 2 || len(s) > dictMaxEntry {
+		return
+	}
+	d.entries[s] = true
+}
+
+func (d *dictCollector) inspect(n ast.Node) bool {
+	switch v := n.(type) {
+	case *ast.CallExpr:
+		name, _ := astGetName(v.Fun)
+		if sel, ok := v.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				name = pkg.Name + "." + sel.Sel.Name
+			}
+		}
+		if dictSkippedCalls[name] {
+			return false
+		}
+	case *ast.ImportSpec:
+		return false
+	case *ast.FuncDecl:
+		// indexed comparisons are grouped by function
+		d.flushIndexed()
+	case *ast.BasicLit:
+		if v.Kind == token.STRING {
+			d.add(constant.MakeFromLiteral(v.Value, v.Kind, 0))
+		}
+	case *ast.CompositeLit:
+		// like []byte{0x89, 'P', 'N', 'G'}
+		d.add(d.e.eval(v, nil, 0))
+	case *ast.BinaryExpr:
+		switch v.Op {
+		case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ:
+			d.add(d.e.eval(v.X, nil, 0))
+			d.add(d.e.eval(v.Y, nil, 0))
+			if v.Op == token.EQL || v.Op == token.NEQ {
+				d.addIndexed(v.X, v.Y)
+				d.addIndexed(v.Y, v.X)
+			}
+		}
+	case *ast.CaseClause:
+		for _, x := range v.List {
+			d.add(d.e.eval(x, nil, 0))
+		}
+	case *ast.ValueSpec:
+		for i := range v.Values {
+			c := d.e.eval(v.Values[i], nil, 0)
+			if c.Kind() == constant.String {
+				d.add(c)
+			}
+		}
+	}
+	return true
+}
+
+// writes a libFuzzer dictionary with the literals and compared constants of the package
+func PackageToDictionary(pkg *packages.Package, w io.StringWriter) error {
+	d := &dictCollector{entries: make(map[string]bool), indexed: make(map[string]map[int64]byte)}
+	d.e = &seedExtractor{globals: make(map[string]ast.Expr), types: make(map[string]ast.Expr)}
+	d.e.collectGlobals(pkg.Syntax, true)
+	for _, f := range pkg.Syntax {
+		ast.Inspect(f, d.inspect)
+	}
+	d.flushIndexed()
+	var entries []string
+	for s := range d.entries {
+		entries = append(entries, dictEscape(s))
+	}
+	sort.Strings(entries)
+	w.WriteString(fmt.Sprintf("# dictionary for package %s\n", pkg.ID))
+	for _, s := range entries {
+		_, err := w.WriteString(s + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package pkgtofuzzinput
+
+import "testing"
+
+func TestDictEscape(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"IHDR", `"IHDR"`},
+		{"", `""`},
+		{`a"b`, `"a\"b"`},
+		{`a\b`, `"a\\b"`},
+		{"\x89PNG\r\n\x1a\n", `"\x89PNG\x0D\x0A\x1A\x0A"`},
+		{"\x00\x7f\xff", `"\x00\x7F\xFF"`},
+		{"é", `"\xC3\xA9"`},
+	}
+	for _, tt := range tests {
+		if got := dictEscape(tt.s); got != tt.want {
+			t.Errorf("dictEscape(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
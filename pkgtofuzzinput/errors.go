@@ -0,0 +1,53 @@
+package pkgtofuzzinput
+
+import "fmt"
+
+// what the fuzz target does after a call returned an error
+type ErrorsMode int
+
+const (
+	// the rest of the input is not run
+	ErrorsStop ErrorsMode = iota
+	// the next calls get run, and may use objects which returned an error
+	ErrorsContinue
+	// like continue, with errors checked for consistency
+	ErrorsCheck
+)
+
+func ParseErrorsMode(s string) (ErrorsMode, error) {
+	switch s {
+	case "", "stop":
+		return ErrorsStop, nil
+	case "continue":
+		return ErrorsContinue, nil
+	case "check":
+		return ErrorsCheck, nil
+	}
+	return ErrorsStop, fmt.Errorf("Bad errors mode %s, expected stop, continue or check", s)
+}
+
+// statements ending the handling of an error in the fuzz target
+// stopping still runs the observers, after the loop on the calls
+func (e ErrorsMode) statement(indent string) string {
+	if e == ErrorsStop {
+		return indent + "ngoloStatus = 0\n" + indent + "break ngoloCalls\n"
+	}
+	return indent + "continue\n"
+}
+
+// checks an error returned by the fuzzed package
+const fuzzTargetErrors = `
+// panic value of inconsistent errors, not expected by any panic policy
+type ngoloInconsistentError string
+
+// an error describes itself, always the same way
+func ngoloCheckError(err error) {
+	msg := err.Error()
+	if len(msg) == 0 {
+		panic(ngoloInconsistentError("error with an empty message"))
+	}
+	if err.Error() != msg {
+		panic(ngoloInconsistentError("error with a changing message : " + msg))
+	}
+}
+`
@@ -0,0 +1,248 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// finds the function named like Decode, png.Decode or Reader.Read
+func focusFunction(descr PkgDescription, pkgname string, focus string) (int, error) {
+	parts := strings.Split(focus, ".")
+	if len(parts) > 1 && parts[0] == pkgname {
+		parts = parts[1:]
+	}
+	recv := ""
+	switch len(parts) {
+	case 1:
+	case 2:
+		recv = parts[0] + "Ngdot"
+	default:
+		return 0, fmt.Errorf("Bad function name to focus on %s", focus)
+	}
+	for i, m := range descr.Functions {
+		if m.Name == parts[len(parts)-1] && m.Recv == recv {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Function to focus on %s not found", focus)
+}
+
+// chooses, for each produced type, the function producing it with the fewest calls
+func focusProducers(descr PkgDescription) map[string]int {
+	cost := make(map[string]int)
+	best := make(map[string]int)
+	for changed := true; changed; {
+		changed = false
+		for i, m := range descr.Functions {
+			c := 1
+			for a := range m.Args {
+				if m.Args[a].Proto != PkgFuncArgClassPkgGen {
+					continue
+				}
+				ac, ok := cost[m.Args[a].FieldType]
+				if !ok {
+					c = 0
+					break
+				}
+				c = c + ac
+			}
+			if c == 0 {
+				continue
+			}
+			for _, r := range m.Returns {
+				if !r.Used || r.FieldType == "error" {
+					continue
+				}
+				prev, ok := cost[r.FieldType]
+				if !ok || c < prev {
+					cost[r.FieldType] = c
+					best[r.FieldType] = i
+					changed = true
+				}
+			}
+		}
+	}
+	return best
+}
+
+// appends the calls producing the arguments of function f
+func focusChain(descr PkgDescription, best map[string]int, f int, chain []int) ([]int, error) {
+	m := descr.Functions[f]
+	for a := range m.Args {
+		if m.Args[a].Proto != PkgFuncArgClassPkgGen {
+			continue
+		}
+		p, ok := best[m.Args[a].FieldType]
+		if !ok {
+			return nil, fmt.Errorf("No function produces %s for %s", m.Args[a].FieldType, QualifiedName(m))
+		}
+		var err error
+		// costs are decreasing, so this terminates
+		chain, err = focusChain(descr, best, p, chain)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, p)
+	}
+	return chain, nil
+}
+
+// keeps only the function to focus on, and the ones building its arguments
+// returns the indexes of the calls to make, the focused function being the last one
+func FocusDescription(descr PkgDescription, pkgname string, focus string) (PkgDescription, []int, error) {
+	f, err := focusFunction(descr, pkgname, focus)
+	if err != nil {
+		return descr, nil, err
+	}
+	chain, err := focusChain(descr, focusProducers(descr), f, nil)
+	if err != nil {
+		return descr, nil, err
+	}
+	chain = append(chain, f)
+
+	used := make(map[int]bool)
+	consumed := make(map[string]bool)
+	for _, c := range chain {
+		used[c] = true
+		for _, a := range descr.Functions[c].Args {
+			if a.Proto == PkgFuncArgClassPkgGen {
+				consumed[a.FieldType] = true
+			}
+		}
+	}
+	r := PkgDescription{}
+	// pools of the types no call of the chain takes would be unused
+	for _, t := range descr.Types {
+		if len(t.Values) > 0 || len(t.Args) > 0 || consumed[t.Name] {
+			r.Types = append(r.Types, t)
+		}
+	}
+	newIndex := make(map[int]int)
+	for i := range descr.Functions {
+		if used[i] {
+			m := descr.Functions[i]
+			m.Returns = append([]PkgFuncResult{}, m.Returns...)
+			for j := range m.Returns {
+				if m.Returns[j].FieldType != "error" && !consumed[m.Returns[j].FieldType] {
+					m.Returns[j].Used = false
+				}
+			}
+			newIndex[i] = len(r.Functions)
+			r.Functions = append(r.Functions, m)
+		}
+	}
+	for i := range chain {
+		chain[i] = newIndex[chain[i]]
+		log.Printf("Focus call %d : %s", i+1, QualifiedName(r.Functions[chain[i]]))
+	}
+	return r, chain, nil
+}
+
+// converts a seed, serialized as a NgoloFuzzList, into a NgoloFuzzFocus
+// the arguments of a call go to the first step of the chain making this call, other calls are dropped
+func focusSeed(data []byte, chain []int) ([]byte, bool) {
+	var r []byte
+	filled := make([]bool, len(chain))
+	found := false
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 || num != 1 || typ != protowire.BytesType {
+			return nil, false
+		}
+		data = data[n:]
+		one, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, false
+		}
+		data = data[n:]
+		num, typ, n = protowire.ConsumeTag(one)
+		if n < 0 || typ != protowire.BytesType {
+			return nil, false
+		}
+		args, n := protowire.ConsumeBytes(one[n:])
+		if n < 0 {
+			return nil, false
+		}
+		for i := range chain {
+			if chain[i] == int(num)-1 && !filled[i] {
+				filled[i] = true
+				found = true
+				r = protowire.AppendTag(r, protowire.Number(i+1), protowire.BytesType)
+				r = protowire.AppendBytes(r, args)
+				break
+			}
+		}
+	}
+	return r, found
+}
+
+// one message with the arguments of all the calls, which the fuzzer does not need to order
+func PackageToFocusProtobuf(descr PkgDescription, chain []int, w io.StringWriter) error {
+	w.WriteString("\n\nmessage NgoloFuzzFocus {\n")
+	for i, c := range chain {
+		m := descr.Functions[c]
+		name := fmt.Sprintf("step%d", i+1)
+		if i == len(chain)-1 {
+			name = "focus"
+		}
+		w.WriteString(fmt.Sprintf("  %s%sArgs %s = %d;\n", m.Recv, m.Name, name, i+1))
+	}
+	w.WriteString("}\n")
+	return nil
+}
+
+const fuzzTargetFocus = `
+// corpus files and crashing inputs are NgoloFuzzFocus messages
+func ngoloDecode(data []byte) (*NgoloFuzzList, error) {
+	gen := &NgoloFuzzFocus{}
+	err := proto.Unmarshal(data, gen)
+	if err != nil {
+		return nil, err
+	}
+	return FocusNG_List(gen), nil
+}
+
+// we are unsure the input is a valid protobuf
+func FuzzNG_Focus(data []byte) int {
+	gen := &NgoloFuzzFocus{}
+	err := proto.Unmarshal(data, gen)
+	if err != nil {
+		return 0
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if !ngoloPanicExpected(r) {
+				panic(r)
+			}
+		}
+	}()
+	runtime.GC()
+	return ngoloRun(FocusNG_List(gen))
+}
+`
+
+func PackageToFocusTarget(descr PkgDescription, chain []int, w io.StringWriter) error {
+	w.WriteString(fuzzTargetFocus)
+	w.WriteString("\n// calls the functions building the arguments, before the focused one\n")
+	w.WriteString("func FocusNG_List(gen *NgoloFuzzFocus) *NgoloFuzzList {\n")
+	w.WriteString(fmt.Sprintf("\tr := &NgoloFuzzList{List: make([]*NgoloFuzzOne, 0, %d)}\n", len(chain)))
+	for i, c := range chain {
+		m := descr.Functions[c]
+		name := fmt.Sprintf("Step%d", i+1)
+		if i == len(chain)-1 {
+			name = "Focus"
+		}
+		oneof := fmt.Sprintf("%s%s%s", m.Recv, CamelCase(m.Name), m.Suffix)
+		w.WriteString(fmt.Sprintf("\t%s := gen.Get%s()\n", strings.ToLower(name), name))
+		w.WriteString(fmt.Sprintf("\tif %s == nil {\n", strings.ToLower(name)))
+		w.WriteString(fmt.Sprintf("\t\t%s = &%s%sArgs{}\n", strings.ToLower(name), m.Recv, CamelCase(m.Name)))
+		w.WriteString("\t}\n")
+		w.WriteString(fmt.Sprintf("\tr.List = append(r.List, &NgoloFuzzOne{Item: &NgoloFuzzOne_%s{%s: %s}})\n", oneof, oneof, strings.ToLower(name)))
+	}
+	w.WriteString("\treturn r\n}\n")
+	return nil
+}
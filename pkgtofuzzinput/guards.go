@@ -0,0 +1,71 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"io"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// default pixel budget for images decoded after DecodeConfig
+const defaultPixels = 1024 * 1024
+
+// type of the input of DecodeConfig, like io.Reader, if the package has one returning a Config
+func pkgDecodeConfig(pkg *packages.Package) string {
+	for s := range pkg.Syntax {
+		for _, d := range pkg.Syntax[s].Decls {
+			f, ok := d.(*ast.FuncDecl)
+			if !ok || f.Recv != nil || f.Name.Name != "DecodeConfig" {
+				continue
+			}
+			if len(f.Type.Params.List) != 1 || len(f.Type.Params.List[0].Names) > 1 {
+				continue
+			}
+			if f.Type.Results == nil || len(f.Type.Results.List) != 2 {
+				continue
+			}
+			name, _ := astGetName(f.Type.Results.List[0].Type)
+			if name != "Config" && !strings.HasSuffix(name, ".Config") {
+				continue
+			}
+			class, input := GolangArgumentClassName(f.Type.Params.List[0].Type)
+			if class == PkgFuncArgClassProtoGen || class == PkgFuncArgClassProto {
+				return input
+			}
+		}
+	}
+	return ""
+}
+
+// index of the argument which DecodeConfig can check before decoding, or -1
+func decodeGuardArg(m PkgFunction, input string) int {
+	if len(input) == 0 || len(m.Recv) > 0 || !strings.HasPrefix(m.Name, "Decode") || m.Name == "DecodeConfig" {
+		return -1
+	}
+	for a := range m.Args {
+		if m.Args[a].FieldType == input && (m.Args[a].Proto == PkgFuncArgClassProtoGen || m.Args[a].Proto == PkgFuncArgClassProto) {
+			return a
+		}
+	}
+	return -1
+}
+
+// skips decoding images with too many pixels, as their allocation is not a bug
+func writeDecodeGuard(w io.StringWriter, m PkgFunction, a int, pkgImportName string, pixels int, onError string) {
+	input := fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, TitleCase(m.Args[a].Name))
+	if m.Args[a].Proto == PkgFuncArgClassProtoGen {
+		// DecodeConfig consumes its own reader
+		input = fmt.Sprintf("%s(%s)", ProtoGenerators[m.Args[a].FieldType], input)
+	}
+	w.WriteString(fmt.Sprintf("\t\t\tcfg, err := %s.DecodeConfig(%s)\n", pkgImportName, input))
+	w.WriteString("\t\t\tif err != nil {\n")
+	w.WriteString(fmt.Sprintf("\t\t\t\tngoloCover(\"%s\", NgoloCoverError)\n", QualifiedName(m)))
+	w.WriteString(onError)
+	w.WriteString("\t\t\t}\n")
+	w.WriteString(fmt.Sprintf("\t\t\tif cfg.Width < 0 || cfg.Height < 0 || int64(cfg.Width)*int64(cfg.Height) > %d {\n", pixels))
+	w.WriteString(fmt.Sprintf("\t\t\t\tngoloCover(\"%s\", NgoloCoverSkipped)\n", QualifiedName(m)))
+	w.WriteString("\t\t\t\tcontinue\n")
+	w.WriteString("\t\t\t}\n")
+}
@@ -0,0 +1,51 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var hooksPackage = regexp.MustCompile(`(?m)^package\s+\w+`)
+var hooksBuildTag = regexp.MustCompile(`(?m)^//\s*(go:build|\+build)\s.*\n`)
+
+// name of the precondition hook for a function, after its oneof entry
+func preHookName(m PkgFunction) string {
+	return fmt.Sprintf("Pre_%s%s%s", m.Recv, CamelCase(m.Name), m.Suffix)
+}
+
+// copies the go file with the hooks in the fuzz target package, and returns the hooks found
+func PackageToHooks(hooks string, outdir string, ngdir string) (map[string]bool, error) {
+	src, err := os.ReadFile(hooks)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), hooks, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string]bool)
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && strings.HasPrefix(fd.Name.Name, "Pre_") {
+			r[fd.Name.Name] = true
+		}
+	}
+	// same package and build tag as the generated fuzz target
+	code := hooksBuildTag.ReplaceAllString(string(src), "")
+	loc := hooksPackage.FindStringIndex(code)
+	if loc == nil {
+		return nil, fmt.Errorf("No package clause in %s", hooks)
+	}
+	code = code[:loc[0]] + "package " + outdir + code[loc[1]:]
+	code = "//go:build gofuzz\n\n" + strings.TrimLeft(code, "\n")
+	err = os.WriteFile(filepath.Join(ngdir, "ngolo_hooks.go"), []byte(code), 0644)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
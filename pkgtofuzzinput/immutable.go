@@ -0,0 +1,92 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// panic value of functions modifying their input, not expected by any panic policy
+const fuzzTargetImmutable = `
+type ngoloModifiedInput string
+`
+
+// like "Reverse reverses data in place." or "EncodeRune writes into p"
+var docInPlace = regexp.MustCompile(`(?i)\bin[- ]place\b|\bwrites?\b[^.]*\binto\b`)
+
+// returns the sentence of the doc comment saying the function works in place or writes into its argument, if any
+func docInPlaceSentence(f *ast.FuncDecl) string {
+	if f.Doc == nil {
+		return ""
+	}
+	doc := strings.Join(strings.Fields(f.Doc.Text()), " ")
+	for _, sentence := range strings.SplitAfter(doc, ". ") {
+		if docInPlace.MatchString(sentence) {
+			return strings.TrimSpace(sentence)
+		}
+	}
+	return ""
+}
+
+// functions like io.Reader.Read, ReaderAt.ReadAt or io.ReadFull fill their byte slice
+func readsInto(m PkgFunction) bool {
+	return strings.HasPrefix(m.Name, "Read") && len(m.Returns) > 0 && m.Returns[0].FieldType == "int"
+}
+
+// index of the byte slice argument written by functions like utf8.EncodeRune, utf8.AppendRune or binary.PutUvarint, or -1
+func writtenArg(m PkgFunction) int {
+	if !strings.HasPrefix(m.Name, "Put") && !strings.HasPrefix(m.Name, "Encode") && !strings.HasPrefix(m.Name, "Append") {
+		return -1
+	}
+	a := 0
+	if len(m.Args) > 0 && m.Args[0].FieldType+"Ngdot" == m.Recv {
+		a = 1
+	}
+	// but not like hex.EncodeToString(src []byte)
+	if len(m.Args) > a && m.Args[a].Proto == PkgFuncArgClassProto && m.Args[a].FieldType == "bytes" && !isSrcName(m.Args[a].Name) {
+		return a
+	}
+	return -1
+}
+
+// indexes of the byte slice arguments which the function should not modify
+func immutableArgs(m PkgFunction, mutable []*regexp.Regexp) []int {
+	if len(m.InPlaceDoc) > 0 || readsInto(m) || matchesAny(mutable, QualifiedName(m)) {
+		return nil
+	}
+	written := writtenArg(m)
+	var r []int
+	for a := range m.Args {
+		if m.Args[a].Proto != PkgFuncArgClassProto || m.Args[a].FieldType != "bytes" {
+			continue
+		}
+		if a == written || (m.Args[a].Name == m.DstName && m.SrcDst == FNG_DSTSRC_DST|FNG_DSTSRC_SRC) {
+			// written on purpose
+			continue
+		}
+		r = append(r, a)
+	}
+	return r
+}
+
+func immutableProtoArg(m PkgFunction, a int) string {
+	return fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.Args[a].Name))
+}
+
+// copies the byte slices before the call
+func writeImmutableCopies(w io.StringWriter, m PkgFunction, args []int) {
+	for _, a := range args {
+		w.WriteString(fmt.Sprintf("\t\t\tngoloImmutable%d := append([]byte{}, %s...)\n", a, immutableProtoArg(m, a)))
+	}
+}
+
+// checks the byte slices after the call
+func writeImmutableChecks(w io.StringWriter, m PkgFunction, args []int) {
+	for _, a := range args {
+		w.WriteString(fmt.Sprintf("\t\t\tif !bytes.Equal(ngoloImmutable%d, %s) {\n", a, immutableProtoArg(m, a)))
+		w.WriteString(fmt.Sprintf("\t\t\t\tpanic(ngoloModifiedInput(\"%s modified its argument %s\"))\n", QualifiedName(m), m.Args[a].Name))
+		w.WriteString("\t\t\t}\n")
+	}
+}
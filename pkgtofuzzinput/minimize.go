@@ -0,0 +1,207 @@
+package pkgtofuzzinput
+
+// delta-debugs a crashing list of calls, keeping the same panic location
+const fuzzTargetMinimize = `
+// location of a panic, as the first function out of the runtime
+func ngoloPanicLocation() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return ""
+}
+
+// runs the list of calls and returns the location of an unexpected panic, if any
+func ngoloCrashLocation(gen *NgoloFuzzList) (location string) {
+	defer func() {
+		if r := recover(); r != nil && !ngoloPanicExpected(r) {
+			location = ngoloPanicLocation()
+		}
+	}()
+	ngoloRun(gen)
+	return ""
+}
+
+func ngoloShrinkBytes(b []byte, try func([]byte) bool) []byte {
+	if len(b) > 0 && try(nil) {
+		return nil
+	}
+	for chunk := len(b) / 2; chunk >= 1; chunk = chunk / 2 {
+		for i := 0; i+chunk <= len(b); {
+			c := append(append([]byte{}, b[:i]...), b[i+chunk:]...)
+			if try(c) {
+				b = c
+			} else {
+				i += chunk
+			}
+		}
+	}
+	return b
+}
+
+func ngoloShrinkInt(v int64, try func(int64) bool) int64 {
+	if v != 0 && try(0) {
+		return 0
+	}
+	for d := v / 2; d != 0; d = d / 2 {
+		for v-d != 0 && try(v-d) {
+			v = v - d
+		}
+	}
+	return v
+}
+
+func ngoloShrinkUint(v uint64, try func(uint64) bool) uint64 {
+	if v != 0 && try(0) {
+		return 0
+	}
+	for d := v / 2; d != 0; d = d / 2 {
+		for v-d != 0 && try(v-d) {
+			v = v - d
+		}
+	}
+	return v
+}
+
+// shrinks every field of the message, as long as check still succeeds
+func ngoloShrinkMessage(m protoreflect.Message, check func() bool) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		old := m.Get(fd)
+		if fd.IsMap() {
+			m.Clear(fd)
+			if !check() {
+				m.Set(fd, old)
+			}
+			continue
+		}
+		if fd.IsList() {
+			for j := old.List().Len() - 1; j >= 0; j-- {
+				cur := m.Get(fd)
+				l := m.NewField(fd).List()
+				for k := 0; k < cur.List().Len(); k++ {
+					if k != j {
+						l.Append(cur.List().Get(k))
+					}
+				}
+				if l.Len() == 0 {
+					m.Clear(fd)
+				} else {
+					m.Set(fd, protoreflect.ValueOfList(l))
+				}
+				if !check() {
+					m.Set(fd, cur)
+				}
+			}
+			if m.Has(fd) && fd.Kind() == protoreflect.MessageKind {
+				l := m.Get(fd).List()
+				for k := 0; k < l.Len(); k++ {
+					ngoloShrinkMessage(l.Get(k).Message(), check)
+				}
+			}
+			continue
+		}
+		switch fd.Kind() {
+		case protoreflect.MessageKind:
+			if fd.ContainingOneof() == nil || fd.ContainingOneof().Fields().Len() > 1 && fd.ContainingOneof().Name() != "item" {
+				m.Clear(fd)
+				if check() {
+					continue
+				}
+				m.Set(fd, old)
+			}
+			ngoloShrinkMessage(m.Mutable(fd).Message(), check)
+		case protoreflect.BytesKind:
+			b := ngoloShrinkBytes(old.Bytes(), func(c []byte) bool {
+				m.Set(fd, protoreflect.ValueOfBytes(c))
+				return check()
+			})
+			m.Set(fd, protoreflect.ValueOfBytes(b))
+		case protoreflect.StringKind:
+			b := ngoloShrinkBytes([]byte(old.String()), func(c []byte) bool {
+				m.Set(fd, protoreflect.ValueOfString(string(c)))
+				return check()
+			})
+			m.Set(fd, protoreflect.ValueOfString(string(b)))
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			v := ngoloShrinkInt(old.Int(), func(c int64) bool {
+				m.Set(fd, protoreflect.ValueOfInt32(int32(c)))
+				return check()
+			})
+			m.Set(fd, protoreflect.ValueOfInt32(int32(v)))
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			v := ngoloShrinkInt(old.Int(), func(c int64) bool {
+				m.Set(fd, protoreflect.ValueOfInt64(c))
+				return check()
+			})
+			m.Set(fd, protoreflect.ValueOfInt64(v))
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+			v := ngoloShrinkUint(old.Uint(), func(c uint64) bool {
+				m.Set(fd, protoreflect.ValueOfUint32(uint32(c)))
+				return check()
+			})
+			m.Set(fd, protoreflect.ValueOfUint32(uint32(v)))
+		case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			v := ngoloShrinkUint(old.Uint(), func(c uint64) bool {
+				m.Set(fd, protoreflect.ValueOfUint64(c))
+				return check()
+			})
+			m.Set(fd, protoreflect.ValueOfUint64(v))
+		default:
+			// bool, enum or floating point
+			m.Clear(fd)
+			if !check() {
+				m.Set(fd, old)
+			}
+		}
+	}
+}
+
+// removes calls, by chunks of decreasing size
+func ngoloShrinkCalls(gen *NgoloFuzzList, check func(*NgoloFuzzList) bool) *NgoloFuzzList {
+	for chunk := len(gen.List) / 2; chunk >= 1; chunk = chunk / 2 {
+		for i := 0; i+chunk <= len(gen.List); {
+			c := &NgoloFuzzList{}
+			c.List = append(append(c.List, gen.List[:i]...), gen.List[i+chunk:]...)
+			if check(c) {
+				gen = c
+			} else {
+				i += chunk
+			}
+		}
+	}
+	return gen
+}
+
+func MinimizeNG_List(gen *NgoloFuzzList) (*NgoloFuzzList, error) {
+	location := ngoloCrashLocation(gen)
+	if len(location) == 0 {
+		return nil, fmt.Errorf("input does not panic")
+	}
+	check := func(c *NgoloFuzzList) bool {
+		return ngoloCrashLocation(c) == location
+	}
+	r := proto.Clone(gen).(*NgoloFuzzList)
+	r = ngoloShrinkCalls(r, check)
+	for i := range r.List {
+		ngoloShrinkMessage(r.List[i].ProtoReflect(), func() bool {
+			return check(r)
+		})
+	}
+	// producers may have become unused
+	r = ngoloShrinkCalls(r, check)
+	return r, nil
+}
+`
@@ -0,0 +1,137 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"io"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// zero-argument methods called on every pooled object at the end of an input, in this order
+var observerMethods = []string{"String", "Error", "MarshalText", "MarshalBinary", "Len", "Close"}
+
+// method sets of the package types, out of the syntax
+type pkgMethodSets struct {
+	// zero-argument methods by type
+	methods map[string]map[string]bool
+	// types embedded in structs and interfaces, whose methods get promoted
+	embeds map[string][]string
+	ifaces map[string]bool
+}
+
+func zeroArgs(t *ast.FuncType) bool {
+	return t.Params == nil || len(t.Params.List) == 0
+}
+
+func newPkgMethodSets(pkg *packages.Package) *pkgMethodSets {
+	r := &pkgMethodSets{methods: make(map[string]map[string]bool), embeds: make(map[string][]string), ifaces: make(map[string]bool)}
+	add := func(t string, m string) {
+		if r.methods[t] == nil {
+			r.methods[t] = make(map[string]bool)
+		}
+		r.methods[t][m] = true
+	}
+	for _, f := range pkg.Syntax {
+		for _, d := range f.Decls {
+			switch v := d.(type) {
+			case *ast.FuncDecl:
+				if v.Recv != nil && zeroArgs(v.Type) {
+					add(funcDeclRecv(v), v.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, s := range v.Specs {
+					ts, ok := s.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					var fields []*ast.Field
+					switch t := ts.Type.(type) {
+					case *ast.StructType:
+						fields = t.Fields.List
+					case *ast.InterfaceType:
+						r.ifaces[ts.Name.Name] = true
+						fields = t.Methods.List
+					}
+					for _, field := range fields {
+						if len(field.Names) == 0 {
+							// only types of this package can be resolved
+							if name, ok := astGetName(field.Type); ok && !strings.Contains(name, ".") {
+								r.embeds[ts.Name.Name] = append(r.embeds[ts.Name.Name], name)
+							}
+						} else if ft, ok := field.Type.(*ast.FuncType); ok && zeroArgs(ft) {
+							for _, n := range field.Names {
+								add(ts.Name.Name, n.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return r
+}
+
+func (s *pkgMethodSets) has(t string, m string, visited map[string]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if s.methods[t][m] {
+		return true
+	}
+	for _, e := range s.embeds[t] {
+		if s.has(e, m, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// fills the observer methods of the pooled types
+func pkgObservers(pkg *packages.Package, types []PkgType, filter *NameFilter) {
+	sets := newPkgMethodSets(pkg)
+	for i := range types {
+		if len(types[i].Values) > 0 || len(types[i].Args) > 0 {
+			continue
+		}
+		types[i].Iface = sets.ifaces[types[i].Name]
+		for _, m := range observerMethods {
+			if sets.has(types[i].Name, m, make(map[string]bool)) && filter.UseObserver(types[i].Name, m) {
+				types[i].Observers = append(types[i].Observers, m)
+			}
+		}
+	}
+}
+
+// calls the observer methods on the pooled objects, which may have been left in an inconsistent state,
+// and closes them only after all of them got observed, as some wrap others, like a csv.Reader on a pooled io.Reader
+func writeObservers(w io.StringWriter, types []PkgType) {
+	for _, closing := range []bool{false, true} {
+		for _, r := range types {
+			var methods []string
+			for _, m := range r.Observers {
+				if (m == "Close") == closing {
+					methods = append(methods, m)
+				}
+			}
+			if len(methods) == 0 {
+				continue
+			}
+			recv := "o"
+			if r.Iface {
+				recv = "(*o)"
+			}
+			w.WriteString(fmt.Sprintf("\tfor _, o := range %sResults {\n", r.Name))
+			for _, m := range methods {
+				w.WriteString(fmt.Sprintf("\t\tngoloCalling = \"%s.%s\"\n", r.Name, m))
+				w.WriteString("\t\tif ngoloRepro != nil {\n")
+				w.WriteString(fmt.Sprintf("\t\t\tngoloRepro.observe(\"%%s.%s()\", ngoloRepro.pooled(o, \".\"))\n", m))
+				w.WriteString("\t\t}\n")
+				w.WriteString(fmt.Sprintf("\t\t%s.%s()\n", recv, m))
+			}
+			w.WriteString("\t}\n")
+		}
+	}
+}
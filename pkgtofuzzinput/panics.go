@@ -0,0 +1,167 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"go/ast"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// what to do with the functions documented to panic
+type DocPanics int
+
+const (
+	// their string and error panics are expected
+	DocPanicsExpect DocPanics = iota
+	// they are not fuzzed
+	DocPanicsExclude
+	// they are fuzzed as the others, with a warning
+	DocPanicsWarn
+)
+
+func ParseDocPanics(s string) (DocPanics, error) {
+	switch s {
+	case "", "expect":
+		return DocPanicsExpect, nil
+	case "exclude":
+		return DocPanicsExclude, nil
+	case "warn":
+		return DocPanicsWarn, nil
+	}
+	return DocPanicsExpect, fmt.Errorf("Bad docpanics %s, expected expect, exclude or warn", s)
+}
+
+var docPanicWord = regexp.MustCompile(`(?i)\bpanics?\b`)
+var docNoPanic = regexp.MustCompile(`(?i)\b(not|never|no longer)\s+panics?\b|n't\s+panic`)
+
+// returns the sentence of the doc comment saying the function panics, if any
+// functions named like MustCompile are assumed to panic
+func docPanicSentence(f *ast.FuncDecl) string {
+	doc := ""
+	if f.Doc != nil {
+		doc = strings.Join(strings.Fields(f.Doc.Text()), " ")
+	}
+	for _, sentence := range strings.SplitAfter(doc, ". ") {
+		if docPanicWord.MatchString(sentence) && !docNoPanic.MatchString(sentence) {
+			return strings.TrimSpace(sentence)
+		}
+	}
+	name := f.Name.Name
+	if strings.HasPrefix(name, "Must") && (len(name) == 4 || unicode.IsUpper(rune(name[4]))) {
+		if i := strings.Index(doc, ". "); i >= 0 {
+			doc = doc[:i+1]
+		}
+		if len(doc) == 0 {
+			return name
+		}
+		return doc
+	}
+	return ""
+}
+
+// expects string and error panics from the functions documented to panic
+func (c *PkgConfig) expectDocPanics(descr PkgDescription) {
+	for _, m := range descr.Functions {
+		if len(m.PanicDoc) > 0 {
+			c.Panics = append(c.Panics, regexp.QuoteMeta(QualifiedName(m))+"=string|error")
+		}
+	}
+}
+
+// panics expected from the functions matching a regular expression
+type panicRule struct {
+	pattern   string
+	functions *regexp.Regexp
+	types     []string
+}
+
+// parses panics like big.ErrNaN for every function, or Repeat=string for some functions
+func (c *PkgConfig) panicRules() ([]string, []panicRule, error) {
+	var global []string
+	var rules []panicRule
+	for _, p := range c.Panics {
+		i := strings.Index(p, "=")
+		if i < 0 {
+			global = append(global, p)
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + p[:i] + ")$")
+		if err != nil {
+			return nil, nil, fmt.Errorf("Bad panic function pattern %s : %s", p, err)
+		}
+		if len(p[i+1:]) == 0 {
+			return nil, nil, fmt.Errorf("Bad panic %s without type", p)
+		}
+		rules = append(rules, panicRule{p[:i], re, strings.Split(p[i+1:], "|")})
+	}
+	return global, rules, nil
+}
+
+// appends the types not already in the list, as a type switch does not allow duplicates
+func appendPanicTypes(types []string, seen map[string]bool, add []string) []string {
+	for _, t := range add {
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// writes the function telling if a recovered panic is expected, and thus not a bug
+func writePanicPolicy(w io.StringWriter, descr PkgDescription, config *PkgConfig) error {
+	global, rules, err := config.panicRules()
+	if err != nil {
+		return err
+	}
+	w.WriteString("// function being called, to know which panics it may raise\n")
+	w.WriteString("var ngoloCalling string\n\n")
+	w.WriteString("func ngoloPanicExpected(r interface{}) bool {\n")
+	used := make([]bool, len(rules))
+	cases := false
+	for _, m := range descr.Functions {
+		seen := make(map[string]bool)
+		for _, t := range global {
+			seen[t] = true
+		}
+		var types []string
+		for i := range rules {
+			if rules[i].functions.MatchString(QualifiedName(m)) {
+				used[i] = true
+				types = appendPanicTypes(types, seen, rules[i].types)
+			}
+		}
+		if len(types) == 0 {
+			continue
+		}
+		if !cases {
+			w.WriteString("\tswitch ngoloCalling {\n")
+			cases = true
+		}
+		w.WriteString(fmt.Sprintf("\tcase %q:\n", QualifiedName(m)))
+		w.WriteString("\t\tswitch r.(type) {\n")
+		w.WriteString(fmt.Sprintf("\t\tcase %s:\n", strings.Join(types, ", ")))
+		w.WriteString("\t\t\treturn true\n")
+		w.WriteString("\t\t}\n")
+	}
+	if cases {
+		w.WriteString("\t}\n")
+	}
+	for i := range rules {
+		if !used[i] {
+			log.Printf("Panic rule %s does not match any function", rules[i].pattern)
+		}
+	}
+	if len(global) > 0 {
+		w.WriteString("\tswitch r.(type) {\n")
+		w.WriteString(fmt.Sprintf("\tcase %s:\n", strings.Join(appendPanicTypes(nil, make(map[string]bool), global), ", ")))
+		w.WriteString("\t\treturn true\n")
+		w.WriteString("\t}\n")
+	}
+	w.WriteString("\treturn false\n")
+	w.WriteString("}\n\n")
+	return nil
+}
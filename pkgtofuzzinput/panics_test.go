@@ -0,0 +1,63 @@
+package pkgtofuzzinput
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const panicsTestSource = `package p
+
+// Compile parses a regular expression.
+// It panics if the expression cannot be parsed.
+func Compile(s string) {}
+
+// Repeat returns count copies of s.
+//
+// It panics if count is negative or if the result of (len(s) * count) overflows.
+func Repeat(s string, count int) {}
+
+// Index returns the index of s. It never panics.
+func Index(s string) {}
+
+// Clone returns a copy. Unlike Compile, it does not panic.
+func Clone(s string) {}
+
+// Sqrt sets z to the square root of x. The function panics if z < 0.
+func Sqrt(z int) {}
+
+// MustCompile is like Compile. It simplifies the initialization of globals.
+func MustCompile(s string) {}
+
+func MustParse(s string) {}
+
+// Mustache renders a template.
+func Mustache(s string) {}
+
+func Undocumented(s string) {}
+`
+
+func TestDocPanicSentence(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "p.go", panicsTestSource, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Compile":      "It panics if the expression cannot be parsed.",
+		"Repeat":       "It panics if count is negative or if the result of (len(s) * count) overflows.",
+		"Index":        "",
+		"Clone":        "",
+		"Sqrt":         "The function panics if z < 0.",
+		"MustCompile":  "MustCompile is like Compile.",
+		"MustParse":    "MustParse",
+		"Mustache":     "",
+		"Undocumented": "",
+	}
+	for _, d := range f.Decls {
+		fd := d.(*ast.FuncDecl)
+		if got := docPanicSentence(fd); got != want[fd.Name.Name] {
+			t.Errorf("docPanicSentence(%s) = %q, want %q", fd.Name.Name, got, want[fd.Name.Name])
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package pkgtofuzzinput
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -54,12 +53,23 @@ type PkgFunction struct {
 	Args    []PkgFuncArg
 	Returns []PkgFuncResult
 	SrcDst  uint8
+	DstName string
+	SrcName string
+	// length helper to size dst, like MaxEncodedLen or Encoding.EncodedLen
+	DstLen string
+	// sentence of the doc comment saying the function panics
+	PanicDoc string
+	// sentence of the doc comment saying the function works in place
+	InPlaceDoc string
 }
 
 type PkgType struct {
 	Name   string
 	Values []string
 	Args   []PkgFuncArg
+	// zero-argument methods like String, called on the pooled objects at the end of an input
+	Observers []string
+	Iface     bool
 }
 
 type PkgDescription struct {
@@ -74,6 +84,8 @@ var ProtoGenerators = map[string]string{
 	"io.Writer":     "bytes.NewBuffer",
 	"bufio.Reader":  "CreateBufioReader",
 	"big.Int":       "CreateBigInt",
+	"big.Float":     "CreateBigFloat",
+	"big.Rat":       "CreateBigRat",
 	"net.Conn":      "CreateFuzzingConn",
 	"int":           "int",
 	"rune":          "GetRune",
@@ -83,6 +95,7 @@ var ProtoGenerators = map[string]string{
 	"uint16":        "uint16",
 	"[]int":         "ConvertIntArray",
 	"[]uint16":      "ConvertUint16Array",
+	"any":           "GetAny",
 }
 
 var ProtoGenerated = map[string]string{
@@ -91,7 +104,9 @@ var ProtoGenerated = map[string]string{
 	"io.Reader":     "bytes",
 	"io.Writer":     "bytes",
 	"bufio.Reader":  "bytes",
-	"big.Int":       "bytes",
+	"big.Int":       "NgoloFuzzBigInt",
+	"big.Float":     "NgoloFuzzBigFloat",
+	"big.Rat":       "NgoloFuzzBigRat",
 	"net.Conn":      "bytes",
 	"int":           "int64",
 	"rune":          "string",
@@ -101,6 +116,7 @@ var ProtoGenerated = map[string]string{
 	"uint16":        "uint32",
 	"[]int":         "repeated int64",
 	"[]uint16":      "repeated int64",
+	"any":           "NgoloFuzzAny",
 }
 
 func GolangArgumentClassName(e ast.Expr) (PkgFuncArgClass, string) {
@@ -117,7 +133,7 @@ func GolangArgumentClassName(e ast.Expr) (PkgFuncArgClass, string) {
 		case "int", "rune", "byte", "uint8", "uint16", "uint":
 			return PkgFuncArgClassProtoGen, i.Name
 		case "any":
-			return PkgFuncArgClassProto, "NgoloFuzzAny"
+			return PkgFuncArgClassProtoGen, "any"
 		}
 	case *ast.FuncType:
 		return PkgFuncArgClassUnhandled, ""
@@ -125,7 +141,7 @@ func GolangArgumentClassName(e ast.Expr) (PkgFuncArgClass, string) {
 		return PkgFuncArgClassUnhandled, ""
 	case *ast.InterfaceType:
 		if len(i.Methods.List) == 0 { // any
-			return PkgFuncArgClassProto, "NgoloFuzzAny"
+			return PkgFuncArgClassProtoGen, "any"
 		}
 		return PkgFuncArgClassUnhandled, ""
 	case *ast.ChanType:
@@ -177,7 +193,7 @@ func GolangArgumentClassName(e ast.Expr) (PkgFuncArgClass, string) {
 			case *ast.Ident:
 				se := fmt.Sprintf("%s.%s", i3.Name, i2.Sel.Name)
 				switch se {
-				case "big.Int", "bufio.Reader":
+				case "big.Int", "big.Float", "big.Rat", "bufio.Reader":
 					return PkgFuncArgClassProtoGen, se
 				}
 			}
@@ -272,6 +288,29 @@ func PackageToProtobuf(pkg *packages.Package, descr PkgDescription, w io.StringW
 	w.WriteString("    bytes BytesArgs = 5;\n")
 	w.WriteString("  }\n}\n")
 
+	//TODO only add these ones if necessary
+	w.WriteString(`message NgoloFuzzBigInt {` + "\n")
+	w.WriteString("  bool neg = 1;\n")
+	w.WriteString("  bytes abs = 2;\n")
+	w.WriteString("}\n")
+	w.WriteString(`enum NgoloFuzzBigFloatKind {` + "\n")
+	w.WriteString("  BigFloatFinite = 0;\n")
+	w.WriteString("  BigFloatZero = 1;\n")
+	w.WriteString("  BigFloatInf = 2;\n")
+	w.WriteString("}\n")
+	w.WriteString(`message NgoloFuzzBigFloat {` + "\n")
+	w.WriteString("  NgoloFuzzBigFloatKind kind = 1;\n")
+	w.WriteString("  bytes mant = 2;\n")
+	w.WriteString("  int32 exp = 3;\n")
+	w.WriteString("  bool neg = 4;\n")
+	w.WriteString("  uint32 prec = 5;\n")
+	w.WriteString("  uint32 mode = 6;\n")
+	w.WriteString("}\n")
+	w.WriteString(`message NgoloFuzzBigRat {` + "\n")
+	w.WriteString("  NgoloFuzzBigInt num = 1;\n")
+	w.WriteString("  NgoloFuzzBigInt denom = 2;\n")
+	w.WriteString("}\n")
+
 	w.WriteString(`message NgoloFuzzList { repeated NgoloFuzzOne list = 1; }`)
 
 	return nil
@@ -283,11 +322,12 @@ package %s
 
 import (
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 `
 
-const fuzzTarget2 = `)
-
+// also used as a helper in reproducers
+const fuzzingConnSource = `
 type FuzzingConn struct {
 	buf    []byte
 	offset int
@@ -352,14 +392,50 @@ func CreateFuzzingConn(a []byte) *FuzzingConn {
 	r.buf = a
 	return r
 }
+`
 
+const fuzzTarget2 = `)
+` + fuzzingConnSource + `
 //TODO only add these functions if needed
-func CreateBigInt(a []byte) *big.Int {
+func CreateBigInt(a *NgoloFuzzBigInt) *big.Int {
 	r := new(big.Int)
-	r.SetBytes(a)
+	r.SetBytes(a.GetAbs())
+	if a.GetNeg() {
+		r.Neg(r)
+	}
+	return r
+}
+
+// precision is limited to avoid huge allocations
+func CreateBigFloat(a *NgoloFuzzBigFloat) *big.Float {
+	r := new(big.Float)
+	r.SetPrec(uint(a.GetPrec() % 0x10001))
+	r.SetMode(big.RoundingMode(a.GetMode() % 6))
+	switch a.GetKind() {
+	case NgoloFuzzBigFloatKind_BigFloatInf:
+		return r.SetInf(a.GetNeg())
+	case NgoloFuzzBigFloatKind_BigFloatZero:
+		if a.GetNeg() {
+			return r.Neg(r)
+		}
+		return r
+	}
+	r.SetMantExp(new(big.Float).SetInt(new(big.Int).SetBytes(a.GetMant())), int(a.GetExp()))
+	if a.GetNeg() {
+		r.Neg(r)
+	}
 	return r
 }
 
+// a zero denominator would panic, use 1 instead
+func CreateBigRat(a *NgoloFuzzBigRat) *big.Rat {
+	denom := CreateBigInt(a.GetDenom())
+	if denom.Sign() == 0 {
+		denom.SetInt64(1)
+	}
+	return new(big.Rat).SetFrac(CreateBigInt(a.GetNum()), denom)
+}
+
 func CreateBufioReader(a []byte) *bufio.Reader {
 	return bufio.NewReader(bytes.NewBuffer(a))
 }
@@ -380,12 +456,44 @@ func ConvertUint16Array(a []int64) []uint16 {
 	return r
 }
 
+// keeps v in [min, max], wrapping around the values out of it
+func ngoloLimit(v int64, min int64, max int64) int64 {
+	if v >= min && v <= max {
+		return v
+	}
+	return min + int64(uint64(v-min)%(uint64(max-min)+1))
+}
+
+// keeps v if it is not greater than max, or wraps it in [0, max]
+func ngoloLimitMax(v int64, max int64) int64 {
+	if v <= max {
+		return v
+	}
+	return int64(uint64(v) % (uint64(max) + 1))
+}
+
 func GetRune(s string) rune {
 	for _, c := range s {
 		return c
 	}
 	return '\x00'
 }
+
+func GetAny(a *NgoloFuzzAny) interface{} {
+	switch i := a.GetItem().(type) {
+	case *NgoloFuzzAny_DoubleArgs:
+		return i.DoubleArgs
+	case *NgoloFuzzAny_Int64Args:
+		return i.Int64Args
+	case *NgoloFuzzAny_BoolArgs:
+		return i.BoolArgs
+	case *NgoloFuzzAny_StringArgs:
+		return i.StringArgs
+	case *NgoloFuzzAny_BytesArgs:
+		return i.BytesArgs
+	}
+	return nil
+}
 `
 
 const fuzzTarget3 = `func FuzzNG_valid(data []byte) int {
@@ -396,16 +504,13 @@ const fuzzTarget3 = `func FuzzNG_valid(data []byte) int {
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			switch r.(type) {
-			case string:
-			//do nothing
-			default:
+			if !ngoloPanicExpected(r) {
 				panic(r)
 			}
 		}
 	}()
 	runtime.GC()
-	return FuzzNG_List(gen)
+	return ngoloRun(gen)
 }
 
 // we are unsure the input is a valid protobuf
@@ -417,16 +522,13 @@ func FuzzNG_unsure(data []byte) int {
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			switch r.(type) {
-			case string:
-			//do nothing
-			default:
+			if !ngoloPanicExpected(r) {
 				panic(r)
 			}
 		}
 	}()
 	runtime.GC()
-	return FuzzNG_List(gen)
+	return ngoloRun(gen)
 }
 
 var initialized bool
@@ -434,18 +536,123 @@ var initialized bool
 func FuzzNG_List(gen *NgoloFuzzList) int {
 	if !initialized {
 		repro := os.Getenv("FUZZ_NG_REPRODUCER")
-		if len(repro) > 0 {
-			f, err := os.Create(repro)
-			if err != nil {
-				log.Fatalf("Failed to open %s : %s", repro, err)
-			} else {
-				PrintNG_List(gen, f)
-			}
+		if len(repro) > 0 && ngoloRepro == nil {
+			// only the first input gets recorded
+			ngoloRepro = newNgoloReproducer(repro)
+			defer func() {
+				// written once, on the way out, even when a call panics
+				ngoloRepro.flush()
+				ngoloRepro = nil
+			}()
 		}
 		initialized = true
 	}
 `
 
+// counts, per function, the calls made when running a corpus
+const fuzzTargetCoverage = `
+type NgoloCoverageCount struct {
+	Inputs  int
+	Calls   int
+	Success int
+	Errors  int
+	Skipped int
+	input   int
+}
+
+const (
+	NgoloCoverCall = iota
+	NgoloCoverSuccess
+	NgoloCoverError
+	NgoloCoverSkipped
+)
+
+var ngoloCoverage map[string]*NgoloCoverageCount
+var ngoloCoverageInput int
+
+func ngoloCover(name string, status int) {
+	if status == NgoloCoverCall {
+		// the panic policy depends on the function being called
+		ngoloCalling = name
+	}
+	if ngoloCoverage == nil {
+		return
+	}
+	c := ngoloCoverage[name]
+	switch status {
+	case NgoloCoverCall:
+		c.Calls++
+		if c.input != ngoloCoverageInput {
+			c.Inputs++
+			c.input = ngoloCoverageInput
+		}
+	case NgoloCoverSuccess:
+		c.Success++
+	case NgoloCoverError:
+		c.Errors++
+	case NgoloCoverSkipped:
+		c.Skipped++
+	}
+}
+
+func ngoloCoverRun(gen *NgoloFuzzList) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+		}
+	}()
+	FuzzNG_List(gen)
+	return false
+}
+
+func CoverageNG_Dir(dir string, w io.StringWriter) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	ngoloCoverage = make(map[string]*NgoloCoverageCount, len(ngoloFunctions))
+	for _, f := range ngoloFunctions {
+		ngoloCoverage[f] = &NgoloCoverageCount{}
+	}
+	defer func() {
+		ngoloCoverage = nil
+	}()
+	ngoloCoverageInput = 0
+	invalid := 0
+	panics := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		gen, err := ngoloDecode(data)
+		if err != nil {
+			invalid++
+			continue
+		}
+		ngoloCoverageInput++
+		if ngoloCoverRun(gen) {
+			panics++
+		}
+	}
+	w.WriteString(fmt.Sprintf("%d inputs, %d invalid, %d panicking\n", ngoloCoverageInput, invalid, panics))
+	w.WriteString(fmt.Sprintf("%-40s %8s %8s %8s %8s %8s %8s\n", "function", "inputs", "calls", "success", "errors", "skipped", "panics"))
+	unreached := 0
+	for _, f := range ngoloFunctions {
+		c := ngoloCoverage[f]
+		if c.Calls == 0 {
+			unreached++
+		}
+		w.WriteString(fmt.Sprintf("%-40s %8d %8d %8d %8d %8d %8d\n", f, c.Inputs, c.Calls, c.Success, c.Errors, c.Skipped, c.Calls-c.Success-c.Errors-c.Skipped))
+	}
+	w.WriteString(fmt.Sprintf("%d functions out of %d never called\n", unreached, len(ngoloFunctions)))
+	return nil
+}
+`
+
 // fix camel case for rare functions not having it like rsa.DecryptPKCS1v15
 
 func CamelUpper(s string) string {
@@ -458,6 +665,14 @@ func CamelCase(s string) string {
 	return badCamel.ReplaceAllStringFunc(s, CamelUpper)
 }
 
+// name of the function as it is written in go, like Buffer.Next
+func QualifiedName(m PkgFunction) string {
+	if len(m.Recv) > 0 {
+		return strings.TrimSuffix(m.Recv, "Ngdot") + "." + m.Name
+	}
+	return m.Name
+}
+
 func TitleCase(s string) string {
 	if len(s) > 0 && s[0] == '_' {
 		return "X" + strings.Title(s[1:])
@@ -465,16 +680,40 @@ func TitleCase(s string) string {
 	return strings.Title(s)
 }
 
-func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.StringWriter, outdir string, limits string) error {
-
-	// maybe args parsing should be done earlier...
-	limitsList := strings.Split(limits, ",")
-	if len(limits) == 0 {
-		limitsList = limitsList[:0]
+func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.StringWriter, outdir string, config *PkgConfig) error {
+	limitsMap, err := config.limitsMap()
+	if err != nil {
+		return err
+	}
+	errorsMode, err := ParseErrorsMode(config.Errors)
+	if err != nil {
+		return err
+	}
+	mutable, err := compileNamePatterns(config.Mutable)
+	if err != nil {
+		return err
+	}
+	slow, err := config.slowBudget()
+	if err != nil {
+		return err
 	}
-	limitsMap := make(map[string]bool, len(limitsList))
-	for k := range limitsList {
-		limitsMap[limitsList[k]] = true
+	limitsUsed := make(map[string]bool)
+	// expression limiting an integer argument, or value itself if it is not limited
+	limitArg := func(key string, arg PkgFuncArg, value string) string {
+		l, ok := limitsMap[key]
+		if !ok {
+			return value
+		}
+		limitsUsed[key] = true
+		if !limitTypes[arg.FieldType] {
+			log.Printf("Limit %s is not on an integer but on %s", key, arg.FieldType)
+			return value
+		}
+		return l.expr(arg.FieldType, value)
+	}
+	decodeInput := ""
+	if config.pixels() > 0 {
+		decodeInput = pkgDecodeConfig(pkg)
 	}
 
 	//maybe we should create AST and generate go from there
@@ -489,9 +728,18 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 	toimport["log"] = true
 	toimport["net"] = true
 	toimport["os"] = true
+	toimport["path/filepath"] = true
 	toimport["time"] = true
 	toimport["runtime"] = true
+	toimport["math"] = true
 	toimport["math/big"] = true
+	toimport["sort"] = true
+	toimport["strings"] = true
+	if config.Determinism {
+		for _, i := range determinismImports {
+			toimport[i] = true
+		}
+	}
 	for _, m := range descr.Functions {
 		for a := range m.Args {
 			switch m.Args[a].Proto {
@@ -538,6 +786,29 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 			w.WriteString("\t}\n")
 			w.WriteString("\treturn r\n")
 			w.WriteString("}\n\n")
+
+			w.WriteString("\nfunc " + r.Name + "NewFromFuzzRepro(p " + r.Name + "Enum) string {\n")
+			w.WriteString("\tngoloRepro.imports[ngoloReproPackage] = true\n")
+			if len(r.Values) > 1 {
+				w.WriteString("\tswitch p {\n")
+				for i := 0; i < len(r.Values)-1; i++ {
+					w.WriteString(fmt.Sprintf("\t\tcase %d:\n", i+1))
+					w.WriteString("\t\t\treturn \"" + pkgImportName + "." + r.Values[i+1] + "\"\n")
+				}
+				w.WriteString("\t}\n")
+			}
+			w.WriteString("\treturn \"" + pkgImportName + "." + r.Values[0] + "\"\n")
+			w.WriteString("}\n\n")
+			w.WriteString("\nfunc Convert" + r.Name + "NewFromFuzzRepro(a []" + r.Name + "Enum) string {\n")
+			w.WriteString("\tr := \"[]" + pkgImportName + "." + r.Name + "{\"\n")
+			w.WriteString("\tfor i := range a {\n")
+			w.WriteString("\t\tif i > 0 {\n")
+			w.WriteString("\t\t\tr = r + \", \"\n")
+			w.WriteString("\t\t}\n")
+			w.WriteString("\t\tr = r + " + r.Name + "NewFromFuzzRepro(a[i])\n")
+			w.WriteString("\t}\n")
+			w.WriteString("\treturn r + \"}\"\n")
+			w.WriteString("}\n\n")
 		} else if len(r.Args) > 0 {
 			w.WriteString("\nfunc " + r.Name + "NewFromFuzz(p *" + r.Name + "Struct) *" + pkgImportName + "." + r.Name + "{\n")
 			w.WriteString("\tif p == nil {\n")
@@ -554,16 +825,53 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 						w.WriteString(fmt.Sprintf("%s(p.%s)", r.Args[i].FieldType+"NewFromFuzz", r.Args[i].Name))
 					}
 				case PkgFuncArgClassProto:
-					w.WriteString(fmt.Sprintf("p.%s%s", r.Args[i].Name, r.Args[i].Suffix))
+					w.WriteString(limitArg(r.Name+"."+r.Args[i].Name, r.Args[i], fmt.Sprintf("p.%s%s", r.Args[i].Name, r.Args[i].Suffix)))
 				case PkgFuncArgClassProtoGen:
-					w.WriteString(fmt.Sprintf("%s(p.%s)", ProtoGenerators[r.Args[i].FieldType], r.Args[i].Name))
+					w.WriteString(limitArg(r.Name+"."+r.Args[i].Name, r.Args[i], fmt.Sprintf("%s(p.%s)", ProtoGenerators[r.Args[i].FieldType], r.Args[i].Name)))
 				}
 				w.WriteString(",\n")
 			}
 			w.WriteString("\t}\n")
 			w.WriteString("}\n\n")
+
+			w.WriteString("\nfunc " + r.Name + "NewFromFuzzRepro(p *" + r.Name + "Struct) string {\n")
+			w.WriteString("\tif p == nil {\n")
+			w.WriteString("\t\treturn \"nil\"\n")
+			w.WriteString("\t}\n")
+			w.WriteString("\tngoloRepro.imports[ngoloReproPackage] = true\n")
+			w.WriteString("\treturn \"&" + pkgImportName + "." + r.Name + "{\" +\n")
+			for i := range r.Args {
+				value := ""
+				switch r.Args[i].Proto {
+				case PkgFuncArgClassProto:
+					value = limitArg(r.Name+"."+r.Args[i].Name, r.Args[i], fmt.Sprintf("p.%s%s", r.Args[i].Name, r.Args[i].Suffix))
+				case PkgFuncArgClassProtoGen:
+					value = limitArg(r.Name+"."+r.Args[i].Name, r.Args[i], fmt.Sprintf("%s(p.%s)", ProtoGenerators[r.Args[i].FieldType], r.Args[i].Name))
+				}
+				w.WriteString(fmt.Sprintf("\t\t\"%s: \" + %s + \", \" +\n", r.Args[i].Name, reproArgExpr(r.Args[i], "p."+r.Args[i].Name, value)))
+			}
+			w.WriteString("\t\t\"}\"\n")
+			w.WriteString("}\n\n")
 		}
 	}
+	if errorsMode == ErrorsCheck {
+		w.WriteString(fuzzTargetErrors)
+	}
+	if slow.base > 0 {
+		writeSlowBudget(w, slow)
+	}
+	if config.Determinism {
+		w.WriteString(fuzzTargetDeterminism)
+	} else {
+		w.WriteString(fuzzTargetRunOnce)
+	}
+	if config.Immutable {
+		w.WriteString(fuzzTargetImmutable)
+	}
+	err = writePanicPolicy(w, descr, config)
+	if err != nil {
+		return err
+	}
 	w.WriteString(fuzzTarget3)
 
 	for _, r := range descr.Types {
@@ -572,25 +880,32 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 			w.WriteString(fmt.Sprintf("\t%sResultsIndex := 0\n", r.Name))
 		}
 	}
+	w.WriteString("\tngoloStatus := 1\n")
+	w.WriteString("ngoloCalls:\n")
 	w.WriteString("\tfor l := range gen.List {\n")
 	w.WriteString("\tif l > 4096 {\n")
-	w.WriteString("\t\treturn 0\n")
+	w.WriteString(ErrorsStop.statement("\t\t"))
 	w.WriteString("\t}\n")
 	w.WriteString("\t\tswitch a := gen.List[l].Item.(type) {\n")
 
 	for _, m := range descr.Functions {
 		w.WriteString(fmt.Sprintf("\t\tcase *NgoloFuzzOne_%s%s%s:\n", m.Recv, CamelCase(m.Name), m.Suffix))
+		w.WriteString(fmt.Sprintf("\t\t\tngoloCover(\"%s\", NgoloCoverCall)\n", QualifiedName(m)))
 		//prepare args
+		limitedArgs := make(map[int]bool)
 		for a := range m.Args {
+			limitKey := fmt.Sprintf("%s%s.%s", m.Recv, m.Name, m.Args[a].Name)
 			switch m.Args[a].Proto {
 			case PkgFuncArgClassPkgGen:
 				w.WriteString(fmt.Sprintf("\t\t\tif len(%sResults) == 0 {\n", m.Args[a].FieldType))
+				w.WriteString(fmt.Sprintf("\t\t\t\tngoloCover(\"%s\", NgoloCoverSkipped)\n", QualifiedName(m)))
 				w.WriteString("\t\t\t\tcontinue\n\t\t\t}\n")
-				w.WriteString(fmt.Sprintf("\t\t\targ%d := %s%sResults[%sResultsIndex]\n", a, m.Args[a].Prefix, m.Args[a].FieldType, m.Args[a].FieldType))
+				w.WriteString(fmt.Sprintf("\t\t\targ%d := %sResults[%sResultsIndex]\n", a, m.Args[a].FieldType, m.Args[a].FieldType))
 				w.WriteString(fmt.Sprintf("\t\t\t%sResultsIndex = (%sResultsIndex + 1) %% len(%sResults)\n", m.Args[a].FieldType, m.Args[a].FieldType, m.Args[a].FieldType))
 			case PkgFuncArgClassProtoGen:
 				w.WriteString(fmt.Sprintf("\t\t\targ%d := ", a))
-				w.WriteString(fmt.Sprintf("%s(a.%s%s%s.%s)\n", ProtoGenerators[m.Args[a].FieldType], m.Recv, CamelCase(m.Name), m.Suffix, TitleCase(m.Args[a].Name)))
+				// check if this parameter must be limited like rand.Prime.bits
+				w.WriteString(limitArg(limitKey, m.Args[a], fmt.Sprintf("%s(a.%s%s%s.%s)", ProtoGenerators[m.Args[a].FieldType], m.Recv, CamelCase(m.Name), m.Suffix, TitleCase(m.Args[a].Name))) + "\n")
 			case PkgFuncArgClassPkgConst:
 				w.WriteString(fmt.Sprintf("\t\t\targ%d := ", a))
 				w.WriteString(fmt.Sprintf("%s(a.%s%s.%s)\n", m.Args[a].FieldType+"NewFromFuzz", m.Recv, m.Name, strings.Title(m.Args[a].Name)))
@@ -598,20 +913,122 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 				w.WriteString(fmt.Sprintf("\t\t\targ%d := ", a))
 				w.WriteString(fmt.Sprintf("%s(a.%s%s.%s)\n", m.Args[a].FieldType+"NewFromFuzz", m.Recv, m.Name, strings.Title(m.Args[a].Name)))
 				w.WriteString(fmt.Sprintf("\t\t\tif arg%d == nil {\n", a))
+				w.WriteString(fmt.Sprintf("\t\t\t\tngoloCover(\"%s\", NgoloCoverSkipped)\n", QualifiedName(m)))
 				w.WriteString("\t\t\t\t continue\n")
 				w.WriteString("\t\t\t}\n")
 			case PkgFuncArgClassProto:
-				// The parameter 2 could be improved
-				if m.Args[a].Name == "dst" && m.Args[a].FieldType == "bytes" && m.SrcDst == FNG_DSTSRC_DST|FNG_DSTSRC_SRC {
-					w.WriteString(fmt.Sprintf("\t\t\ta.%s%s%s.Dst = make([]byte, 2*len(a.%s%s%s.Src))\n", m.Recv, m.Name, m.Suffix, m.Recv, m.Name, m.Suffix))
+				if m.Args[a].Name == m.DstName && m.SrcDst == FNG_DSTSRC_DST|FNG_DSTSRC_SRC {
+					w.WriteString(fmt.Sprintf("\t\t\ta.%s%s%s.%s = make([]byte, %s)\n", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.DstName), dstLenExpr(m, pkgImportName)))
+				}
+				protoArg := fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.Args[a].Name))
+				if limited := limitArg(limitKey, m.Args[a], protoArg); limited != protoArg {
+					w.WriteString(fmt.Sprintf("\t\t\targ%d := %s\n", a, limited))
+					limitedArgs[a] = true
+				}
+			}
+		}
+		if g := decodeGuardArg(m, decodeInput); g >= 0 {
+			writeDecodeGuard(w, m, g, pkgImportName, config.pixels(), errorsMode.statement("\t\t\t\t"))
+		}
+		precondition, hasPrecondition := config.Preconditions[QualifiedName(m)]
+		if hasPrecondition {
+			// every argument gets a name to be used in the precondition
+			for a := range m.Args {
+				if m.Args[a].Proto == PkgFuncArgClassProto && !limitedArgs[a] {
+					w.WriteString(fmt.Sprintf("\t\t\targ%d := a.%s%s%s.%s\n", a, m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.Args[a].Name)))
 				}
 			}
+			w.WriteString(fmt.Sprintf("\t\t\tif !(%s) {\n", precondition))
+			w.WriteString(fmt.Sprintf("\t\t\t\tngoloCover(\"%s\", NgoloCoverSkipped)\n", QualifiedName(m)))
+			w.WriteString("\t\t\t\tcontinue\n")
+			w.WriteString("\t\t\t}\n")
 		}
 		//call
+		callArgs := make([]string, 0, len(m.Args))
+		reproArgs := make([]string, 0, len(m.Args))
+		reproFormat := fmt.Sprintf("%s.%s(", pkgImportName, m.Name)
+		for a := range m.Args {
+			protoArg := fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.Args[a].Name))
+			arg := fmt.Sprintf("arg%d", a)
+			repro := ""
+			switch m.Args[a].Proto {
+			case PkgFuncArgClassProto:
+				if !hasPrecondition && !limitedArgs[a] {
+					arg = protoArg
+				}
+			case PkgFuncArgClassProtoGen:
+				protoArg = fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, TitleCase(m.Args[a].Name))
+			case PkgFuncArgClassPkgConst, PkgFuncArgClassPkgStruct:
+				protoArg = fmt.Sprintf("a.%s%s.%s", m.Recv, m.Name, strings.Title(m.Args[a].Name))
+			case PkgFuncArgClassPkgGen:
+				form := m.Args[a].Prefix
+				if len(m.Recv) > 0 && a == 0 {
+					form = "."
+				}
+				repro = fmt.Sprintf("ngoloRepro.pooled(arg%d, \"%s\")", a, form)
+				arg = m.Args[a].Prefix + arg
+			}
+			if len(repro) == 0 {
+				if m.Args[a].Name == m.DstName && m.SrcDst == FNG_DSTSRC_DST|FNG_DSTSRC_SRC {
+					repro = fmt.Sprintf("fmt.Sprintf(\"make([]byte, %%d)\", len(%s))", protoArg)
+				} else {
+					repro = reproArgExpr(m.Args[a], protoArg, arg)
+				}
+			}
+			if len(m.Recv) > 0 && a == 0 {
+				if m.Args[a].Proto == PkgFuncArgClassPkgStruct {
+					repro = "\"(\" + " + repro + " + \")\""
+				}
+				reproFormat = fmt.Sprintf("%%s.%s(", m.Name)
+			} else {
+				callArgs = append(callArgs, arg)
+				reproFormat = reproFormat + "%s, "
+			}
+			reproArgs = append(reproArgs, repro)
+		}
+		reproFormat = strings.TrimSuffix(reproFormat, ", ") + ")"
+		if config.preHooks[preHookName(m)] {
+			hookArgs := callArgs
+			if len(m.Recv) > 0 {
+				hookArgs = append([]string{"arg0"}, callArgs...)
+			}
+			w.WriteString(fmt.Sprintf("\t\t\tif !%s(%s) {\n", preHookName(m), strings.Join(hookArgs, ", ")))
+			w.WriteString(fmt.Sprintf("\t\t\t\tngoloCover(\"%s\", NgoloCoverSkipped)\n", QualifiedName(m)))
+			w.WriteString("\t\t\t\tcontinue\n")
+			w.WriteString("\t\t\t}\n")
+		}
+		var immutable []int
+		if config.Immutable {
+			if len(m.InPlaceDoc) > 0 {
+				log.Printf("Function %s may modify its input : %q", QualifiedName(m), m.InPlaceDoc)
+			}
+			immutable = immutableArgs(m, mutable)
+			writeImmutableCopies(w, m, immutable)
+		}
+		reproResults := make([]string, len(m.Returns))
+		for a := range m.Returns {
+			if m.Returns[a].Used {
+				reproResults[a] = fmt.Sprintf("%q", m.Returns[a].FieldType)
+			} else {
+				reproResults[a] = `""`
+			}
+		}
+		w.WriteString("\t\t\tif ngoloRepro != nil {\n")
+		w.WriteString(fmt.Sprintf("\t\t\t\tngoloRepro.call([]string{%s}, %q", strings.Join(reproResults, ", "), reproFormat))
+		for _, r := range reproArgs {
+			w.WriteString(", " + r)
+		}
+		w.WriteString(")\n")
+		w.WriteString("\t\t\t}\n")
+
+		if slow.base > 0 {
+			w.WriteString("\t\t\tngoloStart := time.Now()\n")
+		}
 		w.WriteString("\t\t\t")
 		useReturn := false
 		for a := range m.Returns {
-			if m.Returns[a].Used {
+			// every result gets compared by the determinism oracle
+			if m.Returns[a].Used || config.Determinism {
 				useReturn = true
 				break
 			}
@@ -624,7 +1041,7 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 				} else {
 					comma = true
 				}
-				if m.Returns[a].Used {
+				if m.Returns[a].Used || config.Determinism {
 					w.WriteString(fmt.Sprintf("r%d", a))
 				} else {
 					w.WriteString("_")
@@ -637,31 +1054,19 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 		} else {
 			w.WriteString(fmt.Sprintf("%s.", pkgImportName))
 		}
-		w.WriteString(fmt.Sprintf("%s(", m.Name))
-		comma := false
-		for a := range m.Args {
-			if len(m.Recv) > 0 && a == 0 {
-				continue
-			}
-			if comma {
-				w.WriteString(", ")
-			} else {
-				comma = true
-			}
-			switch m.Args[a].Proto {
-			case PkgFuncArgClassProto:
-				w.WriteString(fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.Args[a].Name)))
-			case PkgFuncArgClassPkgGen, PkgFuncArgClassProtoGen, PkgFuncArgClassPkgConst, PkgFuncArgClassPkgStruct:
-				w.WriteString(fmt.Sprintf("arg%d", a))
-			}
-			// check if this parameter must be limited like rand.Prime.bits
-			_, ok := limitsMap[fmt.Sprintf("%s%s.%s", m.Recv, m.Name, m.Args[a].Name)]
-			if ok {
-				// constant is good enough for now
-				w.WriteString(" % 0x10001")
+		w.WriteString(fmt.Sprintf("%s(%s)\n", m.Name, strings.Join(callArgs, ", ")))
+		if slow.base > 0 {
+			writeSlowCheck(w, m, reproFormat, reproArgs)
+		}
+		writeImmutableChecks(w, m, immutable)
+		if config.Determinism {
+			results := make([]string, 0, len(m.Returns)+1)
+			results = append(results, fmt.Sprintf("%q", QualifiedName(m)))
+			for a := range m.Returns {
+				results = append(results, fmt.Sprintf("r%d", a))
 			}
+			w.WriteString(fmt.Sprintf("\t\t\tngoloRecord(%s)\n", strings.Join(results, ", ")))
 		}
-		w.WriteString(")\n")
 		if useReturn {
 			for a := range m.Returns {
 				if m.Returns[a].Used {
@@ -669,10 +1074,24 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 						w.WriteString(fmt.Sprintf("\t\t\tif r%d != nil{\n\t", a))
 					}
 					if m.Returns[a].FieldType == "error" {
-						w.WriteString(fmt.Sprintf("\t\t\tr%d.Error()\n", a))
-						w.WriteString("\t\t\treturn 0\n")
+						w.WriteString(fmt.Sprintf("\t\t\tngoloCover(\"%s\", NgoloCoverError)\n", QualifiedName(m)))
+						if errorsMode == ErrorsCheck {
+							w.WriteString(fmt.Sprintf("\t\t\tngoloCheckError(r%d)\n", a))
+						} else {
+							w.WriteString(fmt.Sprintf("\t\t\tr%d.Error()\n", a))
+						}
+						w.WriteString(errorsMode.statement("\t\t\t"))
 					} else {
 						w.WriteString(fmt.Sprintf("\t\t\t%sResults = append(%sResults, %sr%d%s)\n", m.Returns[a].FieldType, m.Returns[a].FieldType, m.Returns[a].Prefix, a, m.Returns[a].Suffix))
+						w.WriteString("\t\t\tif ngoloRepro != nil {\n")
+						if m.Returns[a].Suffix == "..." {
+							w.WriteString(fmt.Sprintf("\t\t\t\tfor i := range r%d {\n", a))
+							w.WriteString(fmt.Sprintf("\t\t\t\t\tngoloRepro.produced(%d, r%d[i], \"\", i)\n", a, a))
+							w.WriteString("\t\t\t\t}\n")
+						} else {
+							w.WriteString(fmt.Sprintf("\t\t\t\tngoloRepro.produced(%d, %sr%d, \"%s\", -1)\n", a, m.Returns[a].Prefix, a, m.Returns[a].Prefix))
+						}
+						w.WriteString("\t\t\t}\n")
 					}
 					if m.Returns[a].Prefix == "" && m.Returns[a].Suffix == "" {
 						w.WriteString(fmt.Sprintf("\t\t\t}\n"))
@@ -680,214 +1099,108 @@ func PackageToFuzzTarget(pkg *packages.Package, descr PkgDescription, w io.Strin
 				}
 			}
 		}
+		w.WriteString(fmt.Sprintf("\t\t\tngoloCover(\"%s\", NgoloCoverSuccess)\n", QualifiedName(m)))
 	}
-	w.WriteString("\t\t}\n\t}\n\treturn 1\n}\n\n")
-
-	w.WriteString("func PrintNG_List(gen *NgoloFuzzList, w io.StringWriter) {\n")
-	for _, r := range descr.Types {
-		if len(r.Values) == 0 && len(r.Args) == 0 {
-			w.WriteString(fmt.Sprintf("\t%sNb := 0\n", r.Name))
-			w.WriteString(fmt.Sprintf("\t%sResultsIndex := 0\n", r.Name))
+	w.WriteString("\t\t}\n\t}\n")
+	writeObservers(w, descr.Types)
+	w.WriteString("\treturn ngoloStatus\n}\n\n")
+	for k := range limitsMap {
+		if !limitsUsed[k] {
+			log.Printf("Limit %s matches no argument", k)
 		}
 	}
-	w.WriteString("\tfor l := range gen.List {\n")
-	w.WriteString("\t\tswitch a := gen.List[l].Item.(type) {\n")
-	for _, m := range descr.Functions {
-		w.WriteString(fmt.Sprintf("\t\tcase *NgoloFuzzOne_%s%s%s:\n", m.Recv, CamelCase(m.Name), m.Suffix))
-		//prepare args
-		for a := range m.Args {
-			switch m.Args[a].Proto {
-			case PkgFuncArgClassPkgGen:
-				w.WriteString(fmt.Sprintf("\t\t\tif %sNb == 0 {\n", m.Args[a].FieldType))
-				w.WriteString("\t\t\t\tcontinue\n\t\t\t}\n")
-			}
-		}
-		//call
-		useReturn := false
-		for a := range m.Returns {
-			if m.Returns[a].Used && m.Returns[a].FieldType != "error" {
-				useReturn = true
-				break
-			}
-		}
-		formatArgs := make([]string, 0, 16)
-		w.WriteString("\t\t\tw.WriteString(fmt.Sprintf(\"")
-		nbprints := make(map[string]int)
-		if useReturn {
-			comma := false
-			for a := range m.Returns {
-				if comma {
-					w.WriteString(", ")
-				} else {
-					comma = true
-				}
-				if m.Returns[a].Used && m.Returns[a].FieldType != "error" {
-					w.WriteString(fmt.Sprintf("%s%%d", m.Returns[a].FieldType))
-					formatArgs = append(formatArgs, fmt.Sprintf("%sNb", m.Returns[a].FieldType))
-				} else {
-					w.WriteString("_")
-				}
-			}
-			w.WriteString(" := ")
-		}
-		if len(m.Recv) > 0 {
-			if m.Args[0].Proto == PkgFuncArgClassPkgConst || m.Args[0].Proto == PkgFuncArgClassPkgStruct {
-				w.WriteString(fmt.Sprintf("%s(%%#+v).", m.Args[0].FieldType+"NewFromFuzz"))
-				formatArgs = append(formatArgs, fmt.Sprintf("a.%s%s.%s", m.Recv, m.Name, strings.Title(m.Args[0].Name)))
-			} else {
-				w.WriteString(fmt.Sprintf("%s%%d.", m.Args[0].FieldType))
-				formatArgs = append(formatArgs, fmt.Sprintf("%sResultsIndex", m.Args[0].FieldType))
-				nbprints[m.Args[0].FieldType] = 1
-			}
-		} else {
-			w.WriteString(fmt.Sprintf("%s.", pkgImportName))
-		}
-		w.WriteString(fmt.Sprintf("%s(", m.Name))
 
-		comma := false
-		for a := range m.Args {
-			if len(m.Recv) > 0 && a == 0 {
-				continue
-			}
-			if comma {
-				w.WriteString(", ")
-			} else {
-				comma = true
-			}
-			switch m.Args[a].Proto {
-			case PkgFuncArgClassProto:
-				w.WriteString(fmt.Sprintf("%%#+v"))
-				formatArgs = append(formatArgs, fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.Args[a].Name)))
-			case PkgFuncArgClassProtoGen:
-				w.WriteString(fmt.Sprintf("%s(%%#+v)", ProtoGenerators[m.Args[a].FieldType]))
-				formatArgs = append(formatArgs, fmt.Sprintf("a.%s%s%s.%s", m.Recv, CamelCase(m.Name), m.Suffix, TitleCase(m.Args[a].Name)))
-			case PkgFuncArgClassPkgConst, PkgFuncArgClassPkgStruct:
-				w.WriteString(fmt.Sprintf("%s(%%#+v)", m.Args[a].FieldType+"NewFromFuzz"))
-				formatArgs = append(formatArgs, fmt.Sprintf("a.%s%s.%s", m.Recv, m.Name, strings.Title(m.Args[a].Name)))
-			case PkgFuncArgClassPkgGen:
-				w.WriteString(fmt.Sprintf("%s%%d", m.Args[a].FieldType))
-				nbprint := nbprints[m.Args[a].FieldType]
-				formatArgs = append(formatArgs, fmt.Sprintf("(%sResultsIndex + %d) %% %sNb", m.Args[a].FieldType, nbprint, m.Args[a].FieldType))
-				nbprints[m.Args[a].FieldType] = nbprint + 1
-			}
-			_, ok := limitsMap[fmt.Sprintf("%s%s.%s", m.Recv, m.Name, m.Args[a].Name)]
-			if ok {
-				w.WriteString(" %% 0x10001")
-			}
-		}
-		w.WriteString(`)\n"`)
-		for _, f := range formatArgs {
-			w.WriteString(", ")
-			w.WriteString(f)
-		}
-		w.WriteString("))\n")
+	w.WriteString(fmt.Sprintf("const ngoloReproPackage = %q\n", pkg.ID))
+	w.WriteString(fmt.Sprintf("\n// the calls after an error are run\nconst ngoloReproContinue = %t\n", errorsMode != ErrorsStop))
+	w.WriteString(fmt.Sprintf("\nconst ngoloReproFuzzingConn = %q\n", "\n"+fuzzingConnSource))
+	w.WriteString(fuzzTargetReproducer)
 
-		//save
-		if useReturn {
-			for a := range m.Returns {
-				if m.Returns[a].Used && m.Returns[a].FieldType != "error" {
-					w.WriteString(fmt.Sprintf("\t\t\t%sNb = %sNb + 1\n", m.Returns[a].FieldType, m.Returns[a].FieldType))
-				}
-			}
-		}
-		for a := range m.Args {
-			if m.Args[a].Proto == PkgFuncArgClassPkgGen {
-				w.WriteString(fmt.Sprintf("\t\t\t%sResultsIndex = (%sResultsIndex + 1) %% %sNb\n", m.Args[a].FieldType, m.Args[a].FieldType, m.Args[a].FieldType))
-			}
-		}
+	w.WriteString("\nvar ngoloFunctions = []string{\n")
+	for _, m := range descr.Functions {
+		w.WriteString(fmt.Sprintf("\t\"%s\",\n", QualifiedName(m)))
 	}
-	w.WriteString("\t\t}\n\t}\n}\n")
+	w.WriteString("}\n")
+	w.WriteString(fuzzTargetCoverage)
+	w.WriteString(fuzzTargetMinimize)
 
 	return nil
 }
 
-func pkgFunCorpusable(funcname string, decls []ast.Decl, descr PkgDescription) (bool, *PkgFunction) {
-	for d := range decls {
-		switch f := decls[d].(type) {
-		case *ast.FuncDecl:
-			if f.Recv == nil && f.Name.String() == funcname {
-				interesting := false
-				possible := true
-				for f2 := range descr.Functions {
-					if len(descr.Functions[f2].Recv) == 0 && descr.Functions[f2].Name == f.Name.String() {
-						for a := range descr.Functions[f2].Args {
-							switch descr.Functions[f2].Args[a].FieldType {
-							case "string", "io.ReaderAt", "io.Reader", "bufio.Reader":
-								interesting = true
-							case "int32", "int64", "uint32", "uint64":
-								// still possible
-							default:
-								possible = false
-							}
-						}
-						return (interesting && possible), &descr.Functions[f2]
-					}
-				}
-			}
-		}
-	}
-	return false, nil
+// decodes a corpus file or a crashing input
+const fuzzTargetDecode = `
+func ngoloDecode(data []byte) (*NgoloFuzzList, error) {
+	gen := &NgoloFuzzList{}
+	err := proto.Unmarshal(data, gen)
+	return gen, err
 }
+`
 
-func PackageToCorpus(pkg *packages.Package, descr PkgDescription, outdir string) error {
-	for s := range pkg.Syntax {
-		file, err := os.Open(pkg.CompiledGoFiles[s])
-		if err != nil {
-			log.Printf("Failed reading file : %s", err)
-			return err
-		}
-		// create a copy of the file, just adding some lines in it
-		fcopy, err := os.Create(filepath.Join(outdir, filepath.Base(pkg.CompiledGoFiles[s])))
-		if err != nil {
-			log.Printf("Failed creating file : %s", err)
-			return err
-		}
+// generated test file, used as a command to run generated code on a corpus
+const fuzzTargetTest = `//go:build gofuzz
 
-		scanner := bufio.NewScanner(file)
-		// optionally, resize scanner's capacity for lines over 64K, see next example
-		for scanner.Scan() {
-			l := scanner.Text()
-			fcopy.WriteString(l + "\n")
-			if strings.HasPrefix(l, "func ") {
-				funcname := strings.Split(l[5:], "(")[0]
-				ok, nfun := pkgFunCorpusable(funcname, pkg.Syntax[s].Decls, descr)
-				// if function can generate an item for corpus let's do it
-				if ok {
-					mline := fmt.Sprintf("NgoloCorpusMarshal(&NgoloFuzzOne_%s{%s: &%sArgs{", funcname, funcname, funcname)
-					for a := range nfun.Args {
-						argname := nfun.Args[a].Name
-						switch nfun.Args[a].FieldType {
-						case "io.ReaderAt":
-							fcopy.WriteString(fmt.Sprintf("ngolo_%s, _ := io.ReadAll(io.NewSectionReader(%s, 0, 0x100000))\n", nfun.Args[a].Name, nfun.Args[a].Name))
-							argname = "ngolo_" + argname
-						case "io.Reader", "bufio.Reader":
-							fcopy.WriteString(fmt.Sprintf("ngolo_%s, _ := io.ReadAll(%s)\n", nfun.Args[a].Name, nfun.Args[a].Name))
-							argname = "ngolo_" + argname
-						}
-						if a > 0 {
-							mline = mline + ", "
-						}
-						mline = mline + fmt.Sprintf("%s: %s", TitleCase(nfun.Args[a].Name), argname)
-					}
-					mline = mline + "}})\n"
-					fcopy.WriteString(mline)
-					for a := range nfun.Args {
-						switch nfun.Args[a].FieldType {
-						case "io.ReaderAt", "io.Reader", "bufio.Reader":
-							fcopy.WriteString(fmt.Sprintf("%s = bytes.NewReader(ngolo_%s)\n", nfun.Args[a].Name, nfun.Args[a].Name))
-						}
-					}
-				}
-			}
-		}
+package %s
+
+import (
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// FUZZ_NG_COVERAGE=corpus go test -tags gofuzz -v -run NG_Coverage
+func TestNG_Coverage(t *testing.T) {
+	dir := os.Getenv("FUZZ_NG_COVERAGE")
+	if len(dir) == 0 {
+		t.Skip("FUZZ_NG_COVERAGE is not set to a corpus directory")
+	}
+	err := CoverageNG_Dir(dir, os.Stdout)
+	if err != nil {
+		t.Fatalf("Failed coverage of %%s : %%s", dir, err)
+	}
+}
 
-		file.Close()
-		fcopy.Close()
+// FUZZ_NG_MINIMIZE=/path/to/crash go test -tags gofuzz -v -run NG_Minimize
+func TestNG_Minimize(t *testing.T) {
+	input := os.Getenv("FUZZ_NG_MINIMIZE")
+	if len(input) == 0 {
+		t.Skip("FUZZ_NG_MINIMIZE is not set to a crashing input")
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("Failed to read %%s : %%s", input, err)
+	}
+	gen, err := ngoloDecode(data)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal %%s : %%s", input, err)
+	}
+	min, err := MinimizeNG_List(gen)
+	if err != nil {
+		t.Fatalf("Failed to minimize %%s : %%s", input, err)
+	}
+	data, err = proto.Marshal(min)
+	if err != nil {
+		t.Fatalf("Failed to marshal : %%s", err)
+	}
+	err = os.WriteFile(input+".min", data, 0644)
+	if err != nil {
+		t.Fatalf("Failed to write %%s.min : %%s", input, err)
 	}
+	f, err := os.Create(input + ".min.go")
+	if err != nil {
+		t.Fatalf("Failed to create %%s.min.go : %%s", input, err)
+	}
+	defer f.Close()
+	PrintNG_List(min, f)
+	t.Logf("Minimized %%d calls into %%d, with %%s.min.go reproducer", len(gen.List), len(min.List), input)
+}
+`
+
+func PackageToFuzzTargetTest(w io.StringWriter, outdir string) error {
+	w.WriteString(fmt.Sprintf(fuzzTargetTest, outdir))
 	return nil
 }
 
-func PackageToFuzzer(pkgname string, outdir string, exclude string, limits string) error {
+func PackageToFuzzer(pkgname string, outdir string, config *PkgConfig, testcorpus bool, testdata bool, focus string) error {
 	pkg, err := PackageFromName(pkgname)
 	if err != nil {
 		log.Printf("Failed loading package : %s", err)
@@ -906,10 +1219,56 @@ func PackageToFuzzer(pkgname string, outdir string, exclude string, limits strin
 		return err
 	}
 
-	descr, err := PackageToProtobufMessagesDescription(pkg, exclude)
+	err = config.applyGenerators()
+	if err != nil {
+		return err
+	}
+	filter, err := NewNameFilter(config.Include, config.Exclude)
+	if err != nil {
+		return err
+	}
+	docPanics, err := ParseDocPanics(config.DocPanics)
+	if err != nil {
+		return err
+	}
+	descr, err := PackageToProtobufMessagesDescription(pkg, filter, docPanics)
 	if err != nil {
 		return err
 	}
+	var chain []int
+	if len(focus) > 0 {
+		descr, chain, err = FocusDescription(descr, pkg.Syntax[0].Name.Name, focus)
+		if err != nil {
+			return err
+		}
+	}
+	if docPanics == DocPanicsExpect {
+		config.expectDocPanics(descr)
+	}
+
+	if !config.NoAutoLimits {
+		err = config.addAutoLimits(pkg, descr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(config.Hooks) > 0 {
+		config.preHooks, err = PackageToHooks(config.Hooks, outdir, ngdir)
+		if err != nil {
+			log.Printf("Failed copying hooks : %s", err)
+			return err
+		}
+		hooked := make(map[string]bool, len(descr.Functions))
+		for _, m := range descr.Functions {
+			hooked[preHookName(m)] = true
+		}
+		for h := range config.preHooks {
+			if !hooked[h] {
+				log.Printf("Hook %s does not match any function", h)
+			}
+		}
+	}
 
 	ngProtoFilename := filepath.Join(ngdir, "ngolofuzz.proto")
 	f, err := os.Create(ngProtoFilename)
@@ -921,6 +1280,24 @@ func PackageToFuzzer(pkgname string, outdir string, exclude string, limits strin
 	if err != nil {
 		return err
 	}
+	if chain != nil {
+		err = PackageToFocusProtobuf(descr, chain, f)
+		if err != nil {
+			return err
+		}
+	}
+	f.Close()
+
+	ngProtoFilename = filepath.Join(ngdir, "ngolofuzz.dict")
+	f, err = os.Create(ngProtoFilename)
+	if err != nil {
+		log.Printf("Failed creating file : %s", err)
+		return err
+	}
+	err = PackageToDictionary(pkg, f)
+	if err != nil {
+		return err
+	}
 	f.Close()
 
 	ngProtoFilename = filepath.Join(ngdir, "fuzz_ng.go")
@@ -929,7 +1306,27 @@ func PackageToFuzzer(pkgname string, outdir string, exclude string, limits strin
 		log.Printf("Failed creating file : %s", err)
 		return err
 	}
-	err = PackageToFuzzTarget(pkg, descr, f, outdir, limits)
+	err = PackageToFuzzTarget(pkg, descr, f, outdir, config)
+	if err != nil {
+		return err
+	}
+	if chain != nil {
+		err = PackageToFocusTarget(descr, chain, f)
+		if err != nil {
+			return err
+		}
+	} else {
+		f.WriteString(fuzzTargetDecode)
+	}
+	f.Close()
+
+	ngProtoFilename = filepath.Join(ngdir, "fuzz_ng_test.go")
+	f, err = os.Create(ngProtoFilename)
+	if err != nil {
+		log.Printf("Failed creating file : %s", err)
+		return err
+	}
+	err = PackageToFuzzTargetTest(f, outdir)
 	if err != nil {
 		return err
 	}
@@ -941,6 +1338,20 @@ func PackageToFuzzer(pkgname string, outdir string, exclude string, limits strin
 		log.Printf("Failed creating dir %s : %s", cdir, err)
 		return err
 	}
+	nbSeeds, err := CorpusFromTestSources(pkg, descr, chain, cdir)
+	if err != nil {
+		log.Printf("Failed extracting seeds from unit tests : %s", err)
+	} else if nbSeeds > 0 {
+		log.Printf("Extracted %d seeds from unit tests", nbSeeds)
+	}
+	if testdata {
+		nbSeeds, err = CorpusFromTestdata(pkg, descr, chain, cdir)
+		if err != nil {
+			log.Printf("Failed importing testdata : %s", err)
+		} else {
+			log.Printf("Imported %d seeds from testdata", nbSeeds)
+		}
+	}
 	copydir := filepath.Join(ngdir, "copy")
 	err = os.MkdirAll(copydir, 0777)
 	if err != nil {
@@ -950,6 +1361,13 @@ func PackageToFuzzer(pkgname string, outdir string, exclude string, limits strin
 	err = PackageToCorpus(pkg, descr, copydir)
 	if err != nil {
 		log.Printf("Failed creating corpus : %s", err)
+	} else if testcorpus && chain != nil {
+		log.Printf("Corpus from unit tests is not built in focused mode")
+	} else if testcorpus {
+		err = CorpusFromTests(pkg, descr, copydir, cdir)
+		if err != nil {
+			log.Printf("Failed creating corpus from unit tests : %s", err)
+		}
 	}
 
 	return nil
@@ -957,6 +1375,7 @@ func PackageToFuzzer(pkgname string, outdir string, exclude string, limits strin
 
 func PackageFromName(pkgname string) (*packages.Package, error) {
 	cfg := &packages.Config{Mode: packages.NeedFiles | packages.NeedSyntax | packages.NeedCompiledGoFiles}
+	cfg.Fset = token.NewFileSet()
 	pkgs, err := packages.Load(cfg, pkgname)
 	if err != nil {
 		return nil, err
@@ -964,6 +1383,8 @@ func PackageFromName(pkgname string) (*packages.Package, error) {
 	if len(pkgs) != 1 {
 		return nil, fmt.Errorf("Unexpectedly got %d packages", len(pkgs))
 	}
+	// needed to print the syntax, but only set when loading types
+	pkgs[0].Fset = cfg.Fset
 	return pkgs[0], nil
 }
 
@@ -1004,19 +1425,175 @@ func astGetName(a ast.Expr) (string, bool) {
 	return "", false
 }
 
-func funcToUse(name string, excludes []string) bool {
-	//there may be a better test for exported functions
-	if unicode.IsUpper(rune(name[0])) {
-		for e := range excludes {
-			if strings.Contains(name, excludes[e]) {
-				return false
+func isDstName(name string) bool {
+	switch name {
+	case "dst", "out", "buf", "b":
+		return true
+	}
+	return false
+}
+
+func isSrcName(name string) bool {
+	switch name {
+	case "src", "in", "input", "data":
+		return true
+	}
+	return false
+}
+
+// finds exported functions or methods like hex.EncodedLen(n int) int
+func pkgLenHelpers(pkg *packages.Package) map[string]bool {
+	r := make(map[string]bool)
+	for s := range pkg.Syntax {
+		for d := range pkg.Syntax[s].Decls {
+			f, ok := pkg.Syntax[s].Decls[d].(*ast.FuncDecl)
+			if !ok || !strings.HasSuffix(f.Name.Name, "Len") || !unicode.IsUpper(rune(f.Name.Name[0])) {
+				continue
+			}
+			if f.Type.Params.NumFields() != 1 || f.Type.Results.NumFields() != 1 {
+				continue
+			}
+			p, ok := f.Type.Params.List[0].Type.(*ast.Ident)
+			if !ok || p.Name != "int" {
+				continue
+			}
+			p, ok = f.Type.Results.List[0].Type.(*ast.Ident)
+			if !ok || p.Name != "int" {
+				continue
+			}
+			if f.Recv == nil {
+				r[f.Name.Name] = true
+			} else if len(f.Recv.List) == 1 {
+				name, ok := astGetName(f.Recv.List[0].Type)
+				if ok {
+					r[name+"."+f.Name.Name] = true
+				}
 			}
 		}
-		return true
+	}
+	return r
+}
+
+// chooses the length helper for a function with dst and src, methods first
+func dstLenHelper(funcname string, recv string, lenHelpers map[string]bool) string {
+	var candidates []string
+	lname := strings.ToLower(funcname)
+	if strings.Contains(lname, "decode") {
+		candidates = []string{"DecodedLen", "MaxDecodedLen"}
+	} else if strings.Contains(lname, "encode") {
+		candidates = []string{"EncodedLen", "MaxEncodedLen"}
+	}
+	if len(recv) > 0 {
+		for _, c := range candidates {
+			if lenHelpers[recv+"."+c] {
+				return recv + "." + c
+			}
+		}
+	}
+	for _, c := range candidates {
+		if lenHelpers[c] {
+			return c
+		}
+	}
+	return ""
+}
+
+func dstLenExpr(m PkgFunction, pkgImportName string) string {
+	src := fmt.Sprintf("len(a.%s%s%s.%s)", m.Recv, CamelCase(m.Name), m.Suffix, strings.Title(m.SrcName))
+	if len(m.DstLen) == 0 {
+		// The parameter 2 could be improved
+		return "2*" + src
+	}
+	helper := strings.Split(m.DstLen, ".")
+	if len(helper) == 2 {
+		return fmt.Sprintf("arg0.%s(%s)", helper[1], src)
+	}
+	return fmt.Sprintf("%s.%s(%s)", pkgImportName, m.DstLen, src)
+}
+
+// regular expressions matching whole names like Decode, Buffer.Next or Regexp
+type NameFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func compileNamePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var r []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("Bad pattern %s : %s", p, err)
+		}
+		r = append(r, re)
+	}
+	return r, nil
+}
+
+// an empty include list includes everything
+func NewNameFilter(include []string, exclude []string) (*NameFilter, error) {
+	var err error
+	r := &NameFilter{}
+	r.include, err = compileNamePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	r.exclude, err = compileNamePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func matchesAny(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
 	}
 	return false
 }
 
+// types are only excluded, to keep producing the arguments of the included functions
+func (nf *NameFilter) UseType(name string) bool {
+	//there may be a better test for exported types
+	return unicode.IsUpper(rune(name[0])) && !matchesAny(nf.exclude, name)
+}
+
+// observers are only excluded, as they are not fuzzed by themselves
+func (nf *NameFilter) UseObserver(recv string, name string) bool {
+	return !matchesAny(nf.exclude, recv) && !matchesAny(nf.exclude, recv+"."+name)
+}
+
+// a method is matched by its qualified name or by its receiver type
+func (nf *NameFilter) UseFunction(recv string, name string) bool {
+	if !unicode.IsUpper(rune(name[0])) {
+		return false
+	}
+	qualified := name
+	if len(recv) > 0 {
+		qualified = recv + "." + name
+		if matchesAny(nf.exclude, recv) {
+			return false
+		}
+	}
+	if matchesAny(nf.exclude, qualified) {
+		return false
+	}
+	if len(nf.include) == 0 {
+		return true
+	}
+	return matchesAny(nf.include, qualified) || (len(recv) > 0 && matchesAny(nf.include, recv))
+}
+
+// name of the receiver type, empty for functions
+func funcDeclRecv(f *ast.FuncDecl) string {
+	if f.Recv == nil || len(f.Recv.List) != 1 {
+		return ""
+	}
+	name, _ := astGetName(f.Recv.List[0].Type)
+	return name
+}
+
 func pkgTypeConsts(pkg *packages.Package, k string) (bool, []string) {
 	found := false
 	var values []string
@@ -1112,13 +1689,9 @@ func exportedStructArgs(pkg *packages.Package, sname string, typesMap map[string
 	return r
 }
 
-func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string) (PkgDescription, error) {
+func PackageToProtobufMessagesDescription(pkg *packages.Package, filter *NameFilter, docPanics DocPanics) (PkgDescription, error) {
 	r := PkgDescription{}
 
-	excludes := strings.Split(exclude, ",")
-	if len(exclude) == 0 {
-		excludes = excludes[:0]
-	}
 	typesMap := make(map[string]uint8)
 	//first loop to find exported types
 	for s := range pkg.Syntax {
@@ -1128,7 +1701,7 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 				for l := range f.Specs {
 					switch t := f.Specs[l].(type) {
 					case *ast.TypeSpec:
-						if funcToUse(t.Name.Name, excludes) {
+						if filter.UseType(t.Name.Name) {
 							initVal := uint8(0)
 							switch u := t.Type.(type) {
 							case *ast.StructType:
@@ -1160,7 +1733,7 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 		for d := range pkg.Syntax[s].Decls {
 			switch f := pkg.Syntax[s].Decls[d].(type) {
 			case *ast.FuncDecl:
-				if funcToUse(f.Name.Name, excludes) {
+				if filter.UseFunction(funcDeclRecv(f), f.Name.Name) && (docPanics != DocPanicsExclude || len(docPanicSentence(f)) == 0) {
 					if f.Recv != nil {
 						if len(f.Recv.List) == 1 {
 							name, ok := astGetName(f.Recv.List[0].Type)
@@ -1255,15 +1828,32 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 		}
 	}
 
+	lenHelpers := pkgLenHelpers(pkg)
+
 	// new loop for functions
 	r.Functions = make([]PkgFunction, 0, 16)
 	for s := range pkg.Syntax {
 		for d := range pkg.Syntax[s].Decls {
 			switch f := pkg.Syntax[s].Decls[d].(type) {
 			case *ast.FuncDecl:
-				if funcToUse(f.Name.Name, excludes) {
+				if filter.UseFunction(funcDeclRecv(f), f.Name.Name) {
 					pfpm := PkgFunction{}
 					pfpm.Name = f.Name.Name
+					pfpm.PanicDoc = docPanicSentence(f)
+					pfpm.InPlaceDoc = docInPlaceSentence(f)
+					if len(pfpm.PanicDoc) > 0 {
+						qualified := f.Name.Name
+						if len(funcDeclRecv(f)) > 0 {
+							qualified = funcDeclRecv(f) + "." + f.Name.Name
+						}
+						if docPanics == DocPanicsExclude {
+							log.Printf("Excluding %s documented to panic : %q", qualified, pfpm.PanicDoc)
+							continue
+						}
+						log.Printf("Function %s is documented to panic : %q", qualified, pfpm.PanicDoc)
+					}
+					recvName := ""
+					dstName := ""
 					switch pfpm.Name {
 					case "Marshal", "Unmarshal":
 						pfpm.Suffix = "_"
@@ -1289,6 +1879,7 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 								continue
 							}
 							pfpm.Recv = name + "Ngdot"
+							recvName = name
 							for n := range f.Recv.List[0].Names {
 								papi := PkgFuncArg{}
 								papi.Name = f.Recv.List[0].Names[n].Name
@@ -1340,10 +1931,12 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 								if papi.FieldType == "bytes" {
 									// special handling for functions such as hex.Encode(dst, src []byte)
 									// where dst is write only (no read) and size is assumed to be big enough
-									if papi.Name == "dst" {
-										pfpm.SrcDst = pfpm.SrcDst | FNG_DSTSRC_DST
-									} else if papi.Name == "src" {
+									if isDstName(papi.Name) && len(dstName) == 0 {
+										// only a dst if a src is also found
+										dstName = papi.Name
+									} else if isSrcName(papi.Name) && (pfpm.SrcDst&FNG_DSTSRC_SRC) == 0 {
 										pfpm.SrcDst = pfpm.SrcDst | FNG_DSTSRC_SRC
+										pfpm.SrcName = papi.Name
 									}
 								}
 								pfpm.Args = append(pfpm.Args, papi)
@@ -1353,6 +1946,11 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 					if donotadd {
 						continue
 					}
+					if len(dstName) > 0 && (pfpm.SrcDst&FNG_DSTSRC_SRC) != 0 {
+						pfpm.SrcDst = pfpm.SrcDst | FNG_DSTSRC_DST
+						pfpm.DstName = dstName
+						pfpm.DstLen = dstLenHelper(pfpm.Name, recvName, lenHelpers)
+					}
 					if f.Type.Results != nil {
 						for l := range f.Type.Results.List {
 							name, ok := astGetName(f.Type.Results.List[l].Type)
@@ -1387,5 +1985,6 @@ func PackageToProtobufMessagesDescription(pkg *packages.Package, exclude string)
 			}
 		}
 	}
+	pkgObservers(pkg, r.Types, filter)
 	return r, nil
 }
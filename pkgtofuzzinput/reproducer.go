@@ -0,0 +1,344 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ProtoGeneratorRepro struct {
+	// format applied to the protobuf value
+	Format string
+	// helper source to add to the program, like FuzzingConn
+	Helper  string
+	Imports []string
+}
+
+// how the reproducer writes an argument generated out of a protobuf value
+// types not listed here get printed out of the generated value itself
+var ProtoGeneratorsRepro = map[string]ProtoGeneratorRepro{
+	"io.RuneReader": {"strings.NewReader(%#+v)", "", []string{"strings"}},
+	"io.ReaderAt":   {"bytes.NewReader(%#+v)", "", []string{"bytes"}},
+	"io.Reader":     {"bytes.NewReader(%#+v)", "", []string{"bytes"}},
+	"io.Writer":     {"bytes.NewBuffer(%#+v)", "", []string{"bytes"}},
+	"bufio.Reader":  {"bufio.NewReader(bytes.NewBuffer(%#+v))", "", []string{"bufio", "bytes"}},
+	"net.Conn":      {"CreateFuzzingConn(%#+v)", "FuzzingConn", []string{"io", "net", "time"}},
+}
+
+// returns the expression, in the fuzz target, printing the argument in the reproducer
+// protoArg is the protobuf field, and value is the argument as passed to the function
+func reproArgExpr(arg PkgFuncArg, protoArg string, value string) string {
+	switch arg.Proto {
+	case PkgFuncArgClassProtoGen:
+		r, ok := ProtoGeneratorsRepro[arg.FieldType]
+		if ok {
+			imports := ""
+			for _, i := range r.Imports {
+				imports = imports + fmt.Sprintf(", %q", i)
+			}
+			return fmt.Sprintf("ngoloReproFormat(%q, %s, %q%s)", r.Format, protoArg, r.Helper, imports)
+		}
+	case PkgFuncArgClassPkgConst, PkgFuncArgClassPkgStruct:
+		if strings.HasPrefix(arg.FieldType, "repeated ") {
+			return fmt.Sprintf("Convert%sNewFromFuzzRepro(%s)", arg.FieldType[len("repeated "):], protoArg)
+		}
+		return fmt.Sprintf("%sNewFromFuzzRepro(%s)", arg.FieldType, protoArg)
+	}
+	return fmt.Sprintf("ngoloReproValue(%s)", value)
+}
+
+// records the calls really made by FuzzNG_List and prints them as a go program
+const fuzzTargetReproducer = `
+type ngoloReproVar struct {
+	name string
+	used bool
+}
+
+type ngoloReproCall struct {
+	results  []string
+	vars     []*ngoloReproVar
+	call     string
+	observer bool
+}
+
+type ngoloReproducer struct {
+	file    string
+	test    bool
+	imports map[string]bool
+	helpers map[string]bool
+	calls   []*ngoloReproCall
+	exprs   map[interface{}]string
+	vars    map[interface{}]*ngoloReproVar
+	counts  map[string]int
+	// not recording the calls, only printing one
+	scratch bool
+}
+
+var ngoloRepro *ngoloReproducer
+
+var ngoloReproHelpers = map[string]string{
+	"FuzzingConn": ngoloReproFuzzingConn,
+}
+
+func newNgoloReproducer(file string) *ngoloReproducer {
+	r := &ngoloReproducer{}
+	r.file = file
+	r.test = strings.HasSuffix(file, "_test.go")
+	r.imports = make(map[string]bool)
+	r.helpers = make(map[string]bool)
+	r.exprs = make(map[interface{}]string)
+	r.vars = make(map[interface{}]*ngoloReproVar)
+	r.counts = make(map[string]int)
+	return r
+}
+
+// records a call, before it is made, so that a crash is the last call of the program
+func (r *ngoloReproducer) call(results []string, format string, args ...string) {
+	c := &ngoloReproCall{results: results}
+	c.vars = make([]*ngoloReproVar, len(results))
+	iargs := make([]interface{}, len(args))
+	for i := range args {
+		iargs[i] = args[i]
+	}
+	c.call = fmt.Sprintf(format, iargs...)
+	if !strings.HasPrefix(format, "%s") {
+		// not a method
+		r.imports[ngoloReproPackage] = true
+	}
+	r.calls = append(r.calls, c)
+}
+
+// records an observer call, made at the end of the input even after an error
+func (r *ngoloReproducer) observe(format string, args ...string) {
+	r.call(nil, format, args...)
+	r.calls[len(r.calls)-1].observer = true
+}
+
+// records that the result i of the last call was added to a pool
+func (r *ngoloReproducer) produced(i int, p interface{}, prefix string, elem int) {
+	c := r.calls[len(r.calls)-1]
+	if c.vars[i] == nil {
+		c.vars[i] = &ngoloReproVar{name: fmt.Sprintf("%s%d", c.results[i], r.counts[c.results[i]])}
+		r.counts[c.results[i]]++
+	}
+	expr := prefix + c.vars[i].name
+	if elem >= 0 {
+		expr = fmt.Sprintf("%s[%d]", expr, elem)
+	}
+	r.exprs[p] = expr
+	r.vars[p] = c.vars[i]
+}
+
+// returns the variable for an object taken out of a pool
+// form is "*" to dereference it, and "." to use it as a receiver
+func (r *ngoloReproducer) pooled(p interface{}, form string) string {
+	expr, ok := r.exprs[p]
+	if !ok && r.scratch {
+		t := fmt.Sprintf("%T", p)
+		switch form {
+		case "*":
+			return strings.TrimPrefix(t, "*")
+		case ".":
+			return "(" + t + ")"
+		}
+		return t
+	}
+	if !ok {
+		return "nil"
+	}
+	r.vars[p].used = true
+	switch form {
+	case "*":
+		if strings.HasPrefix(expr, "&") {
+			return expr[1:]
+		}
+		return "*" + expr
+	case ".":
+		return strings.TrimPrefix(expr, "&")
+	}
+	return expr
+}
+
+func (r *ngoloReproducer) flush() {
+	if len(r.file) == 0 {
+		return
+	}
+	err := os.WriteFile(r.file, []byte(r.program()), 0644)
+	if err != nil {
+		log.Fatalf("Failed to write %s : %s", r.file, err)
+	}
+}
+
+func (r *ngoloReproducer) program() string {
+	var body bytes.Buffer
+	hasErr := false
+	// the observers follow the call which stopped the input
+	last := len(r.calls) - 1
+	for last >= 0 && r.calls[last].observer {
+		last--
+	}
+	for i, c := range r.calls {
+		lhs := make([]string, len(c.results))
+		named := false
+		errChecked := false
+		for i := range c.results {
+			lhs[i] = "_"
+			if c.results[i] == "error" && !errChecked {
+				lhs[i] = "err"
+				errChecked = true
+			} else if c.vars[i] != nil && c.vars[i].used {
+				lhs[i] = c.vars[i].name
+				named = true
+			}
+		}
+		body.WriteString("\t")
+		if named {
+			body.WriteString(strings.Join(lhs, ", ") + " := ")
+		} else if errChecked {
+			body.WriteString(strings.Join(lhs, ", ") + " = ")
+		}
+		body.WriteString(c.call + "\n")
+		if errChecked {
+			hasErr = true
+			body.WriteString("\tif err != nil {\n")
+			body.WriteString("\t\tfmt.Println(err.Error())\n")
+			if !ngoloReproContinue && i < last {
+				body.WriteString("\t\treturn\n")
+			}
+			body.WriteString("\t}\n")
+		}
+	}
+
+	imports := make([]string, 0, len(r.imports)+2)
+	for k := range r.imports {
+		imports = append(imports, k)
+	}
+	if hasErr && !r.imports["fmt"] {
+		imports = append(imports, "fmt")
+	}
+	if r.test {
+		imports = append(imports, "testing")
+	}
+	sort.Strings(imports)
+	helpers := make([]string, 0, len(r.helpers))
+	for k := range r.helpers {
+		helpers = append(helpers, k)
+	}
+	sort.Strings(helpers)
+
+	var b bytes.Buffer
+	b.WriteString("package main\n\nimport (\n")
+	for _, k := range imports {
+		b.WriteString("\t\"" + k + "\"\n")
+	}
+	b.WriteString(")\n")
+	for _, k := range helpers {
+		b.WriteString(ngoloReproHelpers[k])
+	}
+	if r.test {
+		b.WriteString("\nfunc TestNgoloReproducer(t *testing.T) {\n")
+	} else {
+		b.WriteString("\nfunc main() {\n")
+	}
+	if hasErr {
+		b.WriteString("\tvar err error\n")
+	}
+	b.Write(body.Bytes())
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func ngoloReproFormat(format string, v interface{}, helper string, imports ...string) string {
+	for _, i := range imports {
+		ngoloRepro.imports[i] = true
+	}
+	if len(helper) > 0 {
+		ngoloRepro.helpers[helper] = true
+	}
+	return fmt.Sprintf(format, v)
+}
+
+func ngoloReproFloat(f float64, t string) string {
+	switch {
+	case math.IsNaN(f):
+		ngoloRepro.imports["math"] = true
+		return t + "(math.NaN())"
+	case math.IsInf(f, 0):
+		ngoloRepro.imports["math"] = true
+		if f > 0 {
+			return t + "(math.Inf(1))"
+		}
+		return t + "(math.Inf(-1))"
+	case f == 0 && math.Signbit(f):
+		ngoloRepro.imports["math"] = true
+		return t + "(math.Copysign(0, -1))"
+	}
+	return fmt.Sprintf("%s(%#v)", t, f)
+}
+
+func ngoloReproBigInt(x *big.Int) string {
+	if x.Sign() == 0 {
+		return "new(big.Int)"
+	}
+	r := fmt.Sprintf("new(big.Int).SetBytes(%#v)", x.Bytes())
+	if x.Sign() < 0 {
+		return "new(big.Int).Neg(" + r + ")"
+	}
+	return r
+}
+
+func ngoloReproBigFloat(x *big.Float) string {
+	if x.Prec() == 0 {
+		// only zero or infinity
+		if x.IsInf() {
+			return fmt.Sprintf("new(big.Float).SetMode(big.%s).SetInf(%t)", x.Mode(), x.Signbit())
+		} else if x.Signbit() {
+			return fmt.Sprintf("new(big.Float).SetMode(big.%s).Neg(new(big.Float))", x.Mode())
+		}
+		return fmt.Sprintf("new(big.Float).SetMode(big.%s)", x.Mode())
+	}
+	return fmt.Sprintf("func() *big.Float { r, _ := new(big.Float).SetPrec(%d).SetMode(big.%s).SetString(%q); return r }()", x.Prec(), x.Mode(), x.Text('p', 0))
+}
+
+func ngoloReproValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return ngoloReproFloat(x, "float64")
+	case float32:
+		return ngoloReproFloat(float64(x), "float32")
+	case []float64:
+		r := "[]float64{"
+		for i := range x {
+			if i > 0 {
+				r = r + ", "
+			}
+			r = r + ngoloReproFloat(x[i], "float64")
+		}
+		return r + "}"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%T(%#v)", x, x)
+	case *big.Int:
+		ngoloRepro.imports["math/big"] = true
+		return ngoloReproBigInt(x)
+	case *big.Float:
+		ngoloRepro.imports["math/big"] = true
+		return ngoloReproBigFloat(x)
+	case *big.Rat:
+		ngoloRepro.imports["math/big"] = true
+		return fmt.Sprintf("new(big.Rat).SetFrac(%s, %s)", ngoloReproBigInt(x.Num()), ngoloReproBigInt(x.Denom()))
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// runs the list of calls to print the reproducer program
+func PrintNG_List(gen *NgoloFuzzList, w io.StringWriter) {
+	ngoloRepro = newNgoloReproducer("")
+	defer func() {
+		recover()
+		w.WriteString(ngoloRepro.program())
+		ngoloRepro = nil
+	}()
+	FuzzNG_List(gen)
+}
+`
@@ -0,0 +1,845 @@
+package pkgtofuzzinput
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"log"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// avoids filling the corpus with the rows of one big table
+const seedsMaxPerFunction = 1024
+
+// numbers of the protobuf fields for the arguments, as written by PackageToProtobuf
+// 0 means the argument has no field
+func protoFieldNumbers(m PkgFunction) []protowire.Number {
+	r := make([]protowire.Number, len(m.Args))
+	idx := protowire.Number(1)
+	for a := range m.Args {
+		switch m.Args[a].Proto {
+		case PkgFuncArgClassPkgConst, PkgFuncArgClassProto, PkgFuncArgClassProtoGen, PkgFuncArgClassPkgStruct:
+			r[a] = idx
+			idx = idx + 1
+		}
+	}
+	return r
+}
+
+// appends one argument to its serialized Args message, if the value fits its type
+func appendSeedField(b []byte, num protowire.Number, arg PkgFuncArg, v constant.Value) ([]byte, bool) {
+	fieldType := arg.FieldType
+	if arg.Proto == PkgFuncArgClassProtoGen {
+		fieldType = ProtoGenerated[arg.FieldType]
+		if arg.FieldType == "rune" && v.Kind() == constant.Int {
+			i, ok := constant.Int64Val(v)
+			if !ok || i < 0 || i > utf8.MaxRune {
+				return b, false
+			}
+			v = constant.MakeString(string(rune(i)))
+		}
+	}
+	switch fieldType {
+	case "string", "bytes":
+		if v.Kind() != constant.String {
+			return b, false
+		}
+		if fieldType == "string" && !utf8.ValidString(constant.StringVal(v)) {
+			// protobuf would fail to unmarshal it
+			return b, false
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, constant.StringVal(v)), true
+	case "bool":
+		if v.Kind() != constant.Bool {
+			return b, false
+		}
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(constant.BoolVal(v))), true
+	case "int32", "int64", "uint32", "uint64":
+		i := constant.ToInt(v)
+		if i.Kind() != constant.Int {
+			return b, false
+		}
+		var u uint64
+		if strings.HasPrefix(fieldType, "u") {
+			x, ok := constant.Uint64Val(i)
+			if !ok {
+				return b, false
+			}
+			u = x
+		} else {
+			x, ok := constant.Int64Val(i)
+			if !ok {
+				return b, false
+			}
+			u = uint64(x)
+		}
+		if fieldType == "int32" || fieldType == "uint32" {
+			// value would be truncated
+			if !strings.HasPrefix(fieldType, "u") && int64(u) != int64(int32(u)) || strings.HasPrefix(fieldType, "u") && u > math.MaxUint32 {
+				return b, false
+			}
+		}
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, u), true
+	case "float", "double":
+		f := constant.ToFloat(v)
+		if f.Kind() != constant.Float {
+			return b, false
+		}
+		x, _ := constant.Float64Val(f)
+		if fieldType == "float" {
+			b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+			return protowire.AppendFixed32(b, math.Float32bits(float32(x))), true
+		}
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(x)), true
+	case "NgoloFuzzBigInt":
+		i := constant.ToInt(v)
+		if i.Kind() != constant.Int {
+			return b, false
+		}
+		var bi []byte
+		if constant.Sign(i) < 0 {
+			bi = protowire.AppendTag(bi, 1, protowire.VarintType)
+			bi = protowire.AppendVarint(bi, 1)
+			i = constant.UnaryOp(token.SUB, i, 0)
+		}
+		abs := new(big.Int)
+		switch x := constant.Val(i).(type) {
+		case int64:
+			abs.SetInt64(x)
+		case *big.Int:
+			abs.Set(x)
+		}
+		bi = protowire.AppendTag(bi, 2, protowire.BytesType)
+		bi = protowire.AppendBytes(bi, abs.Bytes())
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, bi), true
+	}
+	return b, false
+}
+
+// serializes a NgoloFuzzList with one call, lists get merged by concatenation
+func appendSeedCall(b []byte, fidx int, args []byte) []byte {
+	var one []byte
+	one = protowire.AppendTag(one, protowire.Number(fidx+1), protowire.BytesType)
+	one = protowire.AppendBytes(one, args)
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, one)
+}
+
+// writes corpus files named by their hash, like the harvested ones
+type seedWriter struct {
+	dir    string
+	seen   map[string]bool
+	counts map[int]int
+	// calls of the focused mode, whose seeds are NgoloFuzzFocus messages
+	focus []int
+	// first error writing a file, stops the extraction
+	err error
+}
+
+func newSeedWriter(dir string, focus []int) *seedWriter {
+	return &seedWriter{dir: dir, seen: make(map[string]bool), counts: make(map[int]int), focus: focus}
+}
+
+func (w *seedWriter) write(fidx int, data []byte) error {
+	if w.focus != nil {
+		var ok bool
+		data, ok = focusSeed(data, w.focus)
+		if !ok {
+			return nil
+		}
+	}
+	hash := sha1.Sum(data)
+	name := hex.EncodeToString(hash[:])
+	if w.seen[name] || w.counts[fidx] >= seedsMaxPerFunction {
+		return nil
+	}
+	w.seen[name] = true
+	w.counts[fidx]++
+	w.err = os.WriteFile(filepath.Join(w.dir, name), data, 0644)
+	return w.err
+}
+
+// expression bound to a range variable, with the type of the table elements
+type seedBinding struct {
+	x ast.Expr
+	t ast.Expr
+}
+
+type seedExtractor struct {
+	descr PkgDescription
+	w     *seedWriter
+	// name of the package in external tests, empty in internal ones or with a dot import
+	alias string
+	// constants and variables with a value, of the package and its tests
+	globals map[string]ast.Expr
+	types   map[string]ast.Expr
+	// local definitions of the function being walked
+	locals map[string]ast.Expr
+	// results of calls, like re in re, err := regexp.Compile(...)
+	results map[string]int
+}
+
+const seedsMaxDepth = 32
+
+func (e *seedExtractor) lookup(name string, env map[string]seedBinding) (seedBinding, bool) {
+	if b, ok := env[name]; ok {
+		return b, true
+	}
+	if x, ok := e.locals[name]; ok && x != nil {
+		return seedBinding{x, nil}, true
+	}
+	if x, ok := e.globals[name]; ok {
+		return seedBinding{x, nil}, true
+	}
+	return seedBinding{}, false
+}
+
+// names of the fields of a struct type, in order
+func (e *seedExtractor) structFields(t ast.Expr) []string {
+	for i := 0; i < seedsMaxDepth; i++ {
+		switch x := t.(type) {
+		case *ast.Ident:
+			t = e.types[x.Name]
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.StructType:
+			var r []string
+			for _, f := range x.Fields.List {
+				if len(f.Names) == 0 {
+					name, _ := astGetName(f.Type)
+					r = append(r, name)
+				}
+				for _, n := range f.Names {
+					r = append(r, n.Name)
+				}
+			}
+			return r
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// resolves an expression to a composite literal, with its type
+func (e *seedExtractor) composite(x ast.Expr, env map[string]seedBinding, depth int) (*ast.CompositeLit, ast.Expr) {
+	if depth > seedsMaxDepth {
+		return nil, nil
+	}
+	switch v := x.(type) {
+	case *ast.CompositeLit:
+		return v, v.Type
+	case *ast.UnaryExpr:
+		if v.Op == token.AND {
+			return e.composite(v.X, env, depth+1)
+		}
+	case *ast.ParenExpr:
+		return e.composite(v.X, env, depth+1)
+	case *ast.Ident:
+		b, ok := e.lookup(v.Name, env)
+		if ok {
+			c, t := e.composite(b.x, env, depth+1)
+			if c != nil && c.Type == nil {
+				t = b.t
+			}
+			return c, t
+		}
+	case *ast.SelectorExpr:
+		f, t := e.field(v, env, depth+1)
+		if f != nil {
+			c, ct := e.composite(f, env, depth+1)
+			if c != nil && c.Type == nil {
+				ct = t
+			}
+			return c, ct
+		}
+	}
+	return nil, nil
+}
+
+// resolves a selector like tt.in, out of a struct literal
+func (e *seedExtractor) field(x *ast.SelectorExpr, env map[string]seedBinding, depth int) (ast.Expr, ast.Expr) {
+	c, t := e.composite(x.X, env, depth+1)
+	if c == nil {
+		return nil, nil
+	}
+	if t == nil {
+		t = c.Type
+	}
+	fields := e.structFields(t)
+	var ft ast.Expr
+	if st, ok := e.resolveType(t).(*ast.StructType); ok {
+		for _, f := range st.Fields.List {
+			for _, n := range f.Names {
+				if n.Name == x.Sel.Name {
+					ft = f.Type
+				}
+			}
+		}
+	}
+	for i, elt := range c.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if k, ok := kv.Key.(*ast.Ident); ok && k.Name == x.Sel.Name {
+				return kv.Value, ft
+			}
+		} else if i < len(fields) && fields[i] == x.Sel.Name {
+			return elt, ft
+		}
+	}
+	return nil, nil
+}
+
+func (e *seedExtractor) resolveType(t ast.Expr) ast.Expr {
+	for i := 0; i < seedsMaxDepth; i++ {
+		switch x := t.(type) {
+		case *ast.Ident:
+			if e.types[x.Name] == nil {
+				return t
+			}
+			t = e.types[x.Name]
+		case *ast.StarExpr:
+			t = x.X
+		default:
+			return t
+		}
+	}
+	return t
+}
+
+// evaluates an expression to a constant, or to an unknown value
+func (e *seedExtractor) eval(x ast.Expr, env map[string]seedBinding, depth int) (r constant.Value) {
+	unknown := constant.MakeUnknown()
+	if depth > seedsMaxDepth {
+		return unknown
+	}
+	defer func() {
+		// like mismatched kinds in a binary operation
+		if recover() != nil {
+			r = unknown
+		}
+	}()
+	switch v := x.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(v.Value, v.Kind, 0)
+	case *ast.ParenExpr:
+		return e.eval(v.X, env, depth+1)
+	case *ast.UnaryExpr:
+		switch v.Op {
+		case token.SUB, token.ADD, token.XOR, token.NOT:
+			return constant.UnaryOp(v.Op, e.eval(v.X, env, depth+1), 0)
+		}
+	case *ast.BinaryExpr:
+		a := e.eval(v.X, env, depth+1)
+		b := e.eval(v.Y, env, depth+1)
+		switch v.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(constant.ToInt(b))
+			if !ok || s > 1024 {
+				return unknown
+			}
+			return constant.Shift(a, v.Op, uint(s))
+		case token.QUO:
+			if a.Kind() == constant.Int && b.Kind() == constant.Int {
+				return constant.BinaryOp(a, token.QUO_ASSIGN, b)
+			}
+			return constant.BinaryOp(a, v.Op, b)
+		case token.ADD, token.SUB, token.MUL, token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+			return constant.BinaryOp(a, v.Op, b)
+		}
+	case *ast.Ident:
+		switch v.Name {
+		case "true":
+			return constant.MakeBool(true)
+		case "false":
+			return constant.MakeBool(false)
+		case "nil":
+			return constant.MakeString("")
+		}
+		b, ok := e.lookup(v.Name, env)
+		if ok {
+			return e.eval(b.x, env, depth+1)
+		}
+	case *ast.SelectorExpr:
+		if i, ok := v.X.(*ast.Ident); ok && len(e.alias) > 0 && i.Name == e.alias {
+			if g, ok := e.globals[v.Sel.Name]; ok {
+				return e.eval(g, env, depth+1)
+			}
+			return unknown
+		}
+		f, _ := e.field(v, env, depth+1)
+		if f != nil {
+			return e.eval(f, env, depth+1)
+		}
+	case *ast.CallExpr:
+		if len(v.Args) == 0 {
+			return unknown
+		}
+		switch fun := v.Fun.(type) {
+		case *ast.Ident:
+			switch fun.Name {
+			case "string":
+				a := e.eval(v.Args[0], env, depth+1)
+				if a.Kind() == constant.Int {
+					i, ok := constant.Int64Val(a)
+					if ok && i >= 0 && i <= utf8.MaxRune {
+						return constant.MakeString(string(rune(i)))
+					}
+					return unknown
+				}
+				return a
+			case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune", "float32", "float64", "bool":
+				return e.eval(v.Args[0], env, depth+1)
+			}
+		case *ast.ArrayType:
+			if name, ok := fun.Elt.(*ast.Ident); ok && fun.Len == nil && (name.Name == "byte" || name.Name == "uint8") {
+				return e.eval(v.Args[0], env, depth+1)
+			}
+		case *ast.SelectorExpr:
+			if pkg, ok := fun.X.(*ast.Ident); ok {
+				switch pkg.Name + "." + fun.Sel.Name {
+				case "strings.NewReader", "bytes.NewReader", "bytes.NewBufferString", "bytes.NewBuffer", "bufio.NewReader", "big.NewInt":
+					return e.eval(v.Args[0], env, depth+1)
+				}
+			}
+		}
+	case *ast.CompositeLit:
+		t, ok := v.Type.(*ast.ArrayType)
+		if !ok || t.Len != nil {
+			return unknown
+		}
+		if name, ok := t.Elt.(*ast.Ident); !ok || (name.Name != "byte" && name.Name != "uint8") {
+			return unknown
+		}
+		data := make([]byte, 0, len(v.Elts))
+		for _, elt := range v.Elts {
+			c, ok := constant.Uint64Val(constant.ToInt(e.eval(elt, env, depth+1)))
+			if !ok || c > 0xFF {
+				return unknown
+			}
+			data = append(data, byte(c))
+		}
+		return constant.MakeString(string(data))
+	}
+	return unknown
+}
+
+// name of a constant of the package, like ModeB or pkg.ModeB
+func (e *seedExtractor) constName(x ast.Expr) string {
+	switch v := x.(type) {
+	case *ast.Ident:
+		if len(e.alias) == 0 {
+			return v.Name
+		}
+	case *ast.SelectorExpr:
+		if i, ok := v.X.(*ast.Ident); ok && len(e.alias) > 0 && i.Name == e.alias {
+			return v.Sel.Name
+		}
+	}
+	return ""
+}
+
+func (e *seedExtractor) enumValue(t string, x ast.Expr, env map[string]seedBinding, depth int) (int, bool) {
+	if depth > seedsMaxDepth {
+		return 0, false
+	}
+	name := e.constName(x)
+	for _, r := range e.descr.Types {
+		if r.Name == t {
+			for i := range r.Values {
+				if r.Values[i] == name {
+					return i, true
+				}
+			}
+		}
+	}
+	// like tt.mode in a table
+	switch v := x.(type) {
+	case *ast.Ident:
+		if b, ok := e.lookup(v.Name, env); ok {
+			return e.enumValue(t, b.x, env, depth+1)
+		}
+	case *ast.SelectorExpr:
+		if f, _ := e.field(v, env, depth+1); f != nil {
+			return e.enumValue(t, f, env, depth+1)
+		}
+	case *ast.ParenExpr:
+		return e.enumValue(t, v.X, env, depth+1)
+	}
+	return 0, false
+}
+
+// serializes the Args message of a call, receiver excluded
+func (e *seedExtractor) seedArgs(m PkgFunction, params []PkgFuncArg, nums []protowire.Number, args []ast.Expr, env map[string]seedBinding) ([]byte, bool) {
+	var b []byte
+	for a := range params {
+		if nums[a] == 0 {
+			if params[a].Proto == PkgFuncArgClassPkgGen {
+				return nil, false
+			}
+			continue
+		}
+		if params[a].Name == m.DstName && m.SrcDst == FNG_DSTSRC_DST|FNG_DSTSRC_SRC {
+			// the fuzz target sizes it
+			continue
+		}
+		if params[a].Proto == PkgFuncArgClassPkgConst {
+			if strings.HasPrefix(params[a].FieldType, "repeated ") {
+				return nil, false
+			}
+			i, ok := e.enumValue(params[a].FieldType, args[a], env, 0)
+			if !ok {
+				return nil, false
+			}
+			b = protowire.AppendTag(b, nums[a], protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(i))
+			continue
+		}
+		v := e.eval(args[a], env, 0)
+		if v.Kind() == constant.Unknown {
+			return nil, false
+		}
+		var ok bool
+		b, ok = appendSeedField(b, nums[a], params[a], v)
+		if !ok {
+			return nil, false
+		}
+	}
+	return b, true
+}
+
+func (e *seedExtractor) function(name string, recv string) (int, bool) {
+	for i, m := range e.descr.Functions {
+		if m.Name == name && m.Recv == recv {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// name of the function called, if it belongs to the package
+func (e *seedExtractor) calledFunction(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		if len(e.alias) == 0 {
+			return f.Name
+		}
+	case *ast.SelectorExpr:
+		if i, ok := f.X.(*ast.Ident); ok && len(e.alias) > 0 && i.Name == e.alias {
+			return f.Sel.Name
+		}
+	}
+	return ""
+}
+
+// seed for a call of a function, with its serialized list
+func (e *seedExtractor) seedCall(call *ast.CallExpr, env map[string]seedBinding) (int, []byte, bool) {
+	if call.Ellipsis.IsValid() {
+		return 0, nil, false
+	}
+	fidx, ok := e.function(e.calledFunction(call.Fun), "")
+	if !ok {
+		return 0, nil, false
+	}
+	m := e.descr.Functions[fidx]
+	if len(m.Args) != len(call.Args) {
+		return 0, nil, false
+	}
+	args, ok := e.seedArgs(m, m.Args, protoFieldNumbers(m), call.Args, env)
+	if !ok {
+		return 0, nil, false
+	}
+	return fidx, appendSeedCall(nil, fidx, args), true
+}
+
+// seed for a call of a method, after the call producing its receiver
+func (e *seedExtractor) seedMethodCall(call *ast.CallExpr, env map[string]seedBinding) (int, []byte, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || call.Ellipsis.IsValid() {
+		return 0, nil, false
+	}
+	// receiver is a constant like ModeB.Apply(...)
+	for fidx, m := range e.descr.Functions {
+		if m.Name != sel.Sel.Name || len(m.Recv) == 0 || len(m.Args) != len(call.Args)+1 || m.Args[0].Proto != PkgFuncArgClassPkgConst {
+			continue
+		}
+		if _, ok := e.enumValue(m.Args[0].FieldType, sel.X, env, 0); !ok {
+			continue
+		}
+		args, ok := e.seedArgs(m, m.Args, protoFieldNumbers(m), append([]ast.Expr{sel.X}, call.Args...), env)
+		if !ok {
+			return 0, nil, false
+		}
+		return fidx, appendSeedCall(nil, fidx, args), true
+	}
+	var producer *ast.CallExpr
+	result := 0
+	switch x := sel.X.(type) {
+	case *ast.CallExpr:
+		producer = x
+	case *ast.Ident:
+		l, ok := e.locals[x.Name]
+		if !ok || l == nil {
+			return 0, nil, false
+		}
+		producer, ok = l.(*ast.CallExpr)
+		if !ok {
+			return 0, nil, false
+		}
+		result = e.results[x.Name]
+	default:
+		return 0, nil, false
+	}
+	pidx, pdata, ok := e.seedCall(producer, env)
+	if !ok {
+		return 0, nil, false
+	}
+	p := e.descr.Functions[pidx]
+	if result >= len(p.Returns) || !p.Returns[result].Used || p.Returns[result].FieldType == "error" {
+		return 0, nil, false
+	}
+	recv := p.Returns[result].FieldType
+	for r := 0; r < result; r++ {
+		if p.Returns[r].FieldType == recv {
+			// the fuzz target would use the first one
+			return 0, nil, false
+		}
+	}
+	fidx, ok := e.function(sel.Sel.Name, recv+"Ngdot")
+	if !ok {
+		return 0, nil, false
+	}
+	m := e.descr.Functions[fidx]
+	if len(m.Args) != len(call.Args)+1 || m.Args[0].Proto != PkgFuncArgClassPkgGen {
+		return 0, nil, false
+	}
+	args, ok := e.seedArgs(m, m.Args[1:], protoFieldNumbers(m)[1:], call.Args, env)
+	if !ok {
+		return 0, nil, false
+	}
+	return fidx, appendSeedCall(pdata, fidx, args), true
+}
+
+// binds the range variables to each element of the table, and walks the loop body
+func (e *seedExtractor) walkRange(rs *ast.RangeStmt, env map[string]seedBinding) {
+	c, t := e.composite(rs.X, env, 0)
+	if c == nil {
+		e.walk(rs.Body, env)
+		return
+	}
+	var keyType, eltType ast.Expr
+	isMap := false
+	switch tt := e.resolveType(t).(type) {
+	case *ast.ArrayType:
+		eltType = tt.Elt
+	case *ast.MapType:
+		keyType = tt.Key
+		eltType = tt.Value
+		isMap = true
+	}
+	for i, elt := range c.Elts {
+		nenv := make(map[string]seedBinding, len(env)+2)
+		for k, v := range env {
+			nenv[k] = v
+		}
+		value := elt
+		var key ast.Expr = &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(i)}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			value = kv.Value
+			if isMap {
+				key = kv.Key
+			}
+		}
+		if k, ok := rs.Key.(*ast.Ident); ok && k.Name != "_" {
+			nenv[k.Name] = seedBinding{key, keyType}
+		}
+		if v, ok := rs.Value.(*ast.Ident); ok && v.Name != "_" {
+			nenv[v.Name] = seedBinding{value, eltType}
+		}
+		e.walk(rs.Body, nenv)
+		if e.w.err != nil {
+			return
+		}
+	}
+}
+
+func (e *seedExtractor) walk(n ast.Node, env map[string]seedBinding) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.RangeStmt:
+			if v != nil {
+				e.walkRange(v, env)
+			}
+			return false
+		case *ast.CallExpr:
+			fidx, data, ok := e.seedCall(v, env)
+			if !ok {
+				fidx, data, ok = e.seedMethodCall(v, env)
+			}
+			if ok {
+				if e.w.write(fidx, data) != nil {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+// records local definitions, the ones defined twice are ambiguous
+func (e *seedExtractor) collectLocals(body *ast.BlockStmt) {
+	e.locals = make(map[string]ast.Expr)
+	e.results = make(map[string]int)
+	define := func(name string, x ast.Expr, result int) {
+		if name == "_" {
+			return
+		}
+		if _, ok := e.locals[name]; ok {
+			e.locals[name] = nil
+			return
+		}
+		e.locals[name] = x
+		e.results[name] = result
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.AssignStmt:
+			if v.Tok != token.DEFINE && v.Tok != token.ASSIGN {
+				return true
+			}
+			for i, l := range v.Lhs {
+				id, ok := l.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if len(v.Rhs) == len(v.Lhs) {
+					define(id.Name, v.Rhs[i], 0)
+				} else if len(v.Rhs) == 1 {
+					define(id.Name, v.Rhs[0], i)
+				}
+			}
+		case *ast.ValueSpec:
+			for i, id := range v.Names {
+				if len(v.Values) == len(v.Names) {
+					define(id.Name, v.Values[i], 0)
+				} else if len(v.Values) == 1 {
+					define(id.Name, v.Values[0], i)
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (e *seedExtractor) collectGlobals(files []*ast.File, internal bool) {
+	for _, f := range files {
+		for _, d := range f.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, s := range gd.Specs {
+				switch v := s.(type) {
+				case *ast.ValueSpec:
+					if len(v.Values) != len(v.Names) {
+						continue
+					}
+					for i, n := range v.Names {
+						e.globals[n.Name] = v.Values[i]
+					}
+				case *ast.TypeSpec:
+					if internal {
+						e.types[v.Name.Name] = v.Type
+					}
+				}
+			}
+		}
+	}
+}
+
+// writes corpus files out of the literal arguments of the calls in the package tests
+// chain is the list of calls of the focused mode, nil otherwise
+func CorpusFromTestSources(pkg *packages.Package, descr PkgDescription, chain []int, cdir string) (int, error) {
+	pkgdir := filepath.Dir(pkg.GoFiles[0])
+	pkgname := pkg.Syntax[0].Name.Name
+	tests, err := filepath.Glob(filepath.Join(pkgdir, "*_test.go"))
+	if err != nil {
+		return 0, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, t := range tests {
+		f, err := parser.ParseFile(fset, t, nil, 0)
+		if err != nil {
+			log.Printf("Failed parsing %s : %s", t, err)
+			continue
+		}
+		files = append(files, f)
+	}
+
+	w := newSeedWriter(cdir, chain)
+	for _, internal := range []bool{true, false} {
+		e := &seedExtractor{descr: descr, w: w}
+		e.globals = make(map[string]ast.Expr)
+		e.types = make(map[string]ast.Expr)
+		// external tests see the package constants but not its types
+		e.collectGlobals(pkg.Syntax, internal)
+		var scope []*ast.File
+		aliases := make(map[*ast.File]string)
+		for _, f := range files {
+			if (f.Name.Name == pkgname) != internal {
+				continue
+			}
+			if internal {
+				scope = append(scope, f)
+				continue
+			}
+			for _, i := range f.Imports {
+				path, _ := strconv.Unquote(i.Path.Value)
+				if path != pkg.ID {
+					continue
+				}
+				scope = append(scope, f)
+				aliases[f] = pkgname
+				if i.Name != nil && i.Name.Name == "." {
+					// dot import works like an internal test
+					aliases[f] = ""
+				} else if i.Name != nil {
+					aliases[f] = i.Name.Name
+				}
+			}
+		}
+		e.collectGlobals(scope, true)
+		for _, f := range scope {
+			e.alias = aliases[f]
+			for _, d := range f.Decls {
+				fd, ok := d.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				e.collectLocals(fd.Body)
+				e.walk(fd.Body, map[string]seedBinding{})
+			}
+		}
+		if w.err != nil {
+			return len(w.seen), w.err
+		}
+	}
+	return len(w.seen), nil
+}
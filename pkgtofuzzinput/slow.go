@@ -0,0 +1,78 @@
+package pkgtofuzzinput
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// time budget of a call, growing with the size of the protobuf input
+type slowBudget struct {
+	base  time.Duration
+	perKB time.Duration
+}
+
+// no base budget means no timing of the calls
+func (c *PkgConfig) slowBudget() (slowBudget, error) {
+	var r slowBudget
+	var err error
+	if len(c.Slow) > 0 {
+		r.base, err = time.ParseDuration(c.Slow)
+		if err != nil {
+			return r, fmt.Errorf("Bad slow budget %s : %s", c.Slow, err)
+		}
+	}
+	if len(c.SlowPerKB) > 0 {
+		r.perKB, err = time.ParseDuration(c.SlowPerKB)
+		if err != nil {
+			return r, fmt.Errorf("Bad slow budget per kilobyte %s : %s", c.SlowPerKB, err)
+		}
+	}
+	return r, nil
+}
+
+// panics for calls taking longer than their budget
+const fuzzTargetSlow = `
+// panic value of slow calls, not expected by any panic policy
+type ngoloSlowCall string
+
+// the whole input counts, as a call like Reader.Read works on the data given to earlier calls
+func ngoloCheckSlow(start time.Time, gen *NgoloFuzzList, call func() string) {
+	elapsed := time.Since(start)
+	if elapsed <= ngoloSlowBase {
+		return
+	}
+	size := proto.Size(gen)
+	budget := ngoloSlowBase + time.Duration(size)*ngoloSlowPerKB/1024
+	if elapsed <= budget {
+		return
+	}
+	if ngoloRepro == nil {
+		// only to print the arguments, with the pooled objects named by their type
+		ngoloRepro = newNgoloReproducer("")
+		ngoloRepro.scratch = true
+		defer func() {
+			ngoloRepro = nil
+		}()
+	}
+	panic(ngoloSlowCall(fmt.Sprintf("%s took %s, more than %s for %d bytes of input", call(), elapsed, budget, size)))
+}
+`
+
+func writeSlowBudget(w io.StringWriter, b slowBudget) {
+	w.WriteString(fmt.Sprintf("\nconst ngoloSlowBase = time.Duration(%d)\n", int64(b.base)))
+	w.WriteString(fmt.Sprintf("const ngoloSlowPerKB = time.Duration(%d)\n", int64(b.perKB)))
+	w.WriteString(fuzzTargetSlow)
+}
+
+// checks the time taken by the call, printed like in the reproducer
+func writeSlowCheck(w io.StringWriter, m PkgFunction, reproFormat string, reproArgs []string) {
+	args := ""
+	if len(reproArgs) > 0 {
+		args = ", " + strings.Join(reproArgs, ", ")
+	}
+	w.WriteString("\t\t\tngoloCheckSlow(ngoloStart, gen, func() string {\n")
+	w.WriteString(fmt.Sprintf("\t\t\t\treturn fmt.Sprintf(%q%s)\n", reproFormat, args))
+	w.WriteString("\t\t\t})\n")
+}
@@ -0,0 +1,143 @@
+package pkgtofuzzinput
+
+import (
+	"bufio"
+	"bytes"
+	"go/constant"
+	"go/parser"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// bigger files would be truncated by the fuzzer anyway
+const testdataMaxSize = 0x100000
+
+const goFuzzHeader = "go test fuzz v1\n"
+
+// arguments which can take the content of a file
+var testdataArgTypes = map[string]bool{
+	"bytes":       true,
+	"string":      true,
+	"io.Reader":   true,
+	"io.ReaderAt": true,
+}
+
+// values of a go test fuzz v1 file, like []byte("abc") or int(3), one per line
+func goFuzzValues(data []byte) ([]constant.Value, bool) {
+	e := &seedExtractor{}
+	var r []constant.Value
+	scanner := bufio.NewScanner(bytes.NewReader(data[len(goFuzzHeader):]))
+	scanner.Buffer(nil, testdataMaxSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		x, err := parser.ParseExpr(line)
+		if err != nil {
+			return nil, false
+		}
+		v := e.eval(x, nil, 0)
+		if v.Kind() == constant.Unknown {
+			// like math.Float64frombits(...)
+			return nil, false
+		}
+		r = append(r, v)
+	}
+	return r, scanner.Err() == nil
+}
+
+// serializes the Args message, taking the values in order for the arguments they fit
+func testdataArgs(m PkgFunction, values []constant.Value) ([]byte, bool) {
+	var b []byte
+	used := false
+	nums := protoFieldNumbers(m)
+	v := 0
+	for a := range m.Args {
+		if m.Args[a].Proto == PkgFuncArgClassPkgGen {
+			return nil, false
+		}
+		if nums[a] == 0 || v >= len(values) {
+			continue
+		}
+		if m.Args[a].Proto != PkgFuncArgClassProto && m.Args[a].Proto != PkgFuncArgClassProtoGen {
+			// default value for constants and structures
+			continue
+		}
+		if m.Args[a].Name == m.DstName && m.SrcDst == FNG_DSTSRC_DST|FNG_DSTSRC_SRC {
+			continue
+		}
+		nb, ok := appendSeedField(b, nums[a], m.Args[a], values[v])
+		if !ok {
+			continue
+		}
+		if testdataArgTypes[m.Args[a].FieldType] && values[v].Kind() == constant.String {
+			used = true
+		}
+		b = nb
+		v++
+	}
+	return b, used
+}
+
+// writes corpus files out of the testdata directory, for each function taking bytes
+func CorpusFromTestdata(pkg *packages.Package, descr PkgDescription, chain []int, cdir string) (int, error) {
+	tdir := filepath.Join(filepath.Dir(pkg.GoFiles[0]), "testdata")
+	if _, err := os.Stat(tdir); err != nil {
+		return 0, nil
+	}
+	w := newSeedWriter(cdir, chain)
+	err := filepath.WalkDir(tdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != tdir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > testdataMaxSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed reading %s : %s", path, err)
+			return nil
+		}
+		values := []constant.Value{constant.MakeString(string(data))}
+		if bytes.HasPrefix(data, []byte(goFuzzHeader)) {
+			var ok bool
+			values, ok = goFuzzValues(data)
+			if !ok {
+				log.Printf("Failed parsing fuzz corpus file %s", path)
+				return nil
+			}
+		}
+		return testdataWrite(w, descr, values)
+	})
+	return len(w.seen), err
+}
+
+func testdataWrite(w *seedWriter, descr PkgDescription, values []constant.Value) error {
+	for fidx, m := range descr.Functions {
+		args, ok := testdataArgs(m, values)
+		if !ok {
+			continue
+		}
+		err := w.write(fidx, appendSeedCall(nil, fidx, args))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package pkgtofuzzinput
+
+import (
+	"go/constant"
+	"go/token"
+	"testing"
+)
+
+func TestGoFuzzValues(t *testing.T) {
+	tests := []struct {
+		data   string
+		values []constant.Value
+		ok     bool
+	}{
+		{goFuzzHeader + "[]byte(\"abc\")\n", []constant.Value{constant.MakeString("abc")}, true},
+		{goFuzzHeader + "[]byte(\"\\xff\\x00\")\nint(3)\n", []constant.Value{constant.MakeString("\xff\x00"), constant.MakeInt64(3)}, true},
+		{goFuzzHeader + "string(\"a\")\n\nbool(true)\n", []constant.Value{constant.MakeString("a"), constant.MakeBool(true)}, true},
+		{goFuzzHeader + "uint8(255)\nint64(-1)\n", []constant.Value{constant.MakeInt64(255), constant.MakeInt64(-1)}, true},
+		{goFuzzHeader, nil, true},
+		{goFuzzHeader + "math.Float64frombits(0x7ff8000000000001)\n", nil, false},
+		{goFuzzHeader + "[]byte(\"abc\"\n", nil, false},
+	}
+	for _, tt := range tests {
+		values, ok := goFuzzValues([]byte(tt.data))
+		if ok != tt.ok {
+			t.Errorf("goFuzzValues(%q) ok = %v, want %v", tt.data, ok, tt.ok)
+			continue
+		}
+		if len(values) != len(tt.values) {
+			t.Errorf("goFuzzValues(%q) = %v, want %v", tt.data, values, tt.values)
+			continue
+		}
+		for i := range values {
+			if !constant.Compare(values[i], token.EQL, tt.values[i]) {
+				t.Errorf("goFuzzValues(%q)[%d] = %v, want %v", tt.data, i, values[i], tt.values[i])
+			}
+		}
+	}
+}
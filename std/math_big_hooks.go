@@ -0,0 +1,17 @@
+//go:build ignore
+
+package hooks
+
+import (
+	"math/big"
+)
+
+// avoid too big timeout exponentiation
+func Pre_IntNgdotExp(arg0, arg1, arg2, arg3 *big.Int) bool {
+	return arg1.BitLen()+arg2.BitLen() <= 1024 || arg3.BitLen() > 1
+}
+
+// avoid undefined behavior of infinite loop
+func Pre_IntNgdotModSqrt(arg0, arg1, arg2 *big.Int) bool {
+	return arg2.ProbablyPrime(20)
+}